@@ -1,6 +1,10 @@
 package conn_pool
 
-import "time"
+import (
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
 
 type BalanceConfig[T Closer] struct {
 	core          int
@@ -10,6 +14,7 @@ type BalanceConfig[T Closer] struct {
 	lowLoadRatio  float64
 	newConn       func(serviceName string) T
 	checkDuration time.Duration
+	logger        plog.FieldLogger
 }
 
 type BalanceOption[T Closer] func(cfg *BalanceConfig[T])
@@ -55,3 +60,10 @@ func CheckDuration[T Closer](checkDuration time.Duration) BalanceOption[T] {
 		cfg.checkDuration = checkDuration
 	}
 }
+
+// Logger 注入 logger，默认使用 plog.Named("conn_pool")
+func Logger[T Closer](logger plog.FieldLogger) BalanceOption[T] {
+	return func(cfg *BalanceConfig[T]) {
+		cfg.logger = logger
+	}
+}
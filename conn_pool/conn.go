@@ -80,6 +80,9 @@ func NewBalance[T Closer](options ...BalanceOption[T]) *Balancer[T] {
 	if cfg.newConn == nil {
 		panic("new conn must need")
 	}
+	if cfg.logger == nil {
+		cfg.logger = plog.Named("conn_pool")
+	}
 
 	balance := &Balancer[T]{
 		list:          make([]*Conn[T], 0),
@@ -125,7 +128,7 @@ func (b *Balancer[T]) release() {
 	for {
 		select {
 		case <-ticker:
-			plog.Debugf("[Balancer] check release")
+			b.logger.Debugf("[Balancer] check release")
 
 			// 连接池没到核心数，或者处于高负载，不释放连接
 			if len(b.list) <= b.core || b.IsHighLoad() {
@@ -146,7 +149,7 @@ func (b *Balancer[T]) release() {
 				continue
 			}
 
-			plog.Debugf("[Balancer] release conn = %#v", conn)
+			b.logger.Debugf("[Balancer] release conn = %#v", conn)
 
 			conn.connect.Close()
 			b.list = b.list[:len(b.list)-1]
@@ -180,7 +183,7 @@ func (b *Balancer[T]) decrBusyCount() {
 func (b *Balancer[T]) newConnect(service string) *Conn[T] {
 	connect := NewConn(b.newConn(service), b)
 
-	plog.Debugf("[Balancer] new connect = %#v", connect)
+	b.logger.Debugf("[Balancer] new connect = %#v", connect)
 
 	b.Lock()
 	defer b.Unlock()
@@ -210,7 +213,7 @@ func (b *Balancer[T]) Connect(service string) *Conn[T] {
 		b.index++
 		conn.use()
 
-		plog.Debugf("[Balancer] use first not busy conn = %#v", conn)
+		b.logger.Debugf("[Balancer] use first not busy conn = %#v", conn)
 		return conn
 	}
 
@@ -219,13 +222,13 @@ func (b *Balancer[T]) Connect(service string) *Conn[T] {
 		b.index++
 		second.use()
 
-		plog.Debugf("[Balancer] use second not busy conn = %#v", conn)
+		b.logger.Debugf("[Balancer] use second not busy conn = %#v", conn)
 		return second
 	}
 
 	b.index++
 	conn.use()
 
-	plog.Debugf("[Balancer] use busy conn = %#v", conn)
+	b.logger.Debugf("[Balancer] use busy conn = %#v", conn)
 	return conn
 }
@@ -3,6 +3,8 @@ package conn_pool
 import (
 	"sync"
 	"time"
+
+	"github.com/yongpi/putil/plog"
 )
 
 type BalancePool[T Closer] struct {
@@ -29,6 +31,9 @@ func NewBalancePool[T Closer](options ...BalanceOption[T]) *BalancePool[T] {
 	if cfg.newConn == nil {
 		panic("new conn must need")
 	}
+	if cfg.logger == nil {
+		cfg.logger = plog.Named("conn_pool")
+	}
 
 	pool.cfg = cfg
 
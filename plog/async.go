@@ -0,0 +1,242 @@
+package plog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelWriter Out 实现了这个接口时，写入会带上日志级别
+type LevelWriter interface {
+	WriteLevel(levelType LevelType, p []byte) (int, error)
+}
+
+// EntryWriter Out 实现了这个接口时，不经过 Formatter，直接写入 entry
+type EntryWriter interface {
+	WriteEntry(entry *Entry) error
+}
+
+// Flusher Out 实现了这个接口时，Fatal 退出之前会调用 Flush
+type Flusher interface {
+	Flush() error
+}
+
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 缓冲满了阻塞等待
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest 缓冲满了丢弃新的日志
+	OverflowDropNewest
+	// OverflowDropLowLevel 缓冲满了优先丢弃级别最低的日志
+	OverflowDropLowLevel
+)
+
+type AsyncConfig struct {
+	bufferSize int
+	overflow   OverflowPolicy
+}
+
+type AsyncOption func(cfg *AsyncConfig)
+
+func BufferSize(size int) AsyncOption {
+	return func(cfg *AsyncConfig) {
+		cfg.bufferSize = size
+	}
+}
+
+func Overflow(policy OverflowPolicy) AsyncOption {
+	return func(cfg *AsyncConfig) {
+		cfg.overflow = policy
+	}
+}
+
+type asyncRecord struct {
+	level LevelType
+	data  []byte
+}
+
+// AsyncWriter 先写到有界的环形缓冲里，由后台协程写到 out
+type AsyncWriter struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	out     io.Writer
+	cfg     *AsyncConfig
+	ring    []asyncRecord
+	head    int
+	count   int
+	writing bool
+	closed  bool
+	dropped int64
+	done    chan struct{}
+	// 关闭之后的写入直接写到 out，用这个锁串行
+	closedMu sync.Mutex
+}
+
+func NewAsyncWriter(out io.Writer, options ...AsyncOption) *AsyncWriter {
+	cfg := &AsyncConfig{bufferSize: 1024}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = 1
+	}
+
+	w := &AsyncWriter{
+		out:  out,
+		cfg:  cfg,
+		ring: make([]asyncRecord, cfg.bufferSize),
+		done: make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(DEBUG, p)
+}
+
+func (w *AsyncWriter) WriteLevel(levelType LevelType, p []byte) (int, error) {
+	// p 来自 bufferPool，写完就会被复用，需要复制一份
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.writeClosed(data)
+	}
+
+	for w.count == len(w.ring) {
+		switch w.cfg.overflow {
+		case OverflowBlock:
+			w.cond.Wait()
+			if w.closed {
+				w.mu.Unlock()
+				return w.writeClosed(data)
+			}
+			continue
+		case OverflowDropLowLevel:
+			if w.dropLowest(levelType) {
+				continue
+			}
+		}
+		w.mu.Unlock()
+		atomic.AddInt64(&w.dropped, 1)
+		return len(p), nil
+	}
+
+	w.ring[(w.head+w.count)%len(w.ring)] = asyncRecord{level: levelType, data: data}
+	w.count++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// writeClosed 关闭之后直接写，不丢日志；等后台协程写完最后一批再写，避免和最后一批并发写 out
+func (w *AsyncWriter) writeClosed(data []byte) (int, error) {
+	<-w.done
+	w.closedMu.Lock()
+	defer w.closedMu.Unlock()
+	return w.out.Write(data)
+}
+
+// dropLowest 丢掉缓冲里级别比 levelType 低的最旧的一条，没有就返回 false
+func (w *AsyncWriter) dropLowest(levelType LevelType) bool {
+	index := -1
+	for i := 0; i < w.count; i++ {
+		record := w.ring[(w.head+i)%len(w.ring)]
+		if record.level > levelType && (index < 0 || record.level > w.ring[(w.head+index)%len(w.ring)].level) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return false
+	}
+
+	for i := index; i < w.count-1; i++ {
+		w.ring[(w.head+i)%len(w.ring)] = w.ring[(w.head+i+1)%len(w.ring)]
+	}
+	w.count--
+	w.ring[(w.head+w.count)%len(w.ring)] = asyncRecord{}
+	atomic.AddInt64(&w.dropped, 1)
+	return true
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	batch := make([]asyncRecord, 0, len(w.ring))
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.mu.Unlock()
+			return
+		}
+
+		batch = batch[:0]
+		for ; w.count > 0; w.count-- {
+			batch = append(batch, w.ring[w.head])
+			w.ring[w.head] = asyncRecord{}
+			w.head = (w.head + 1) % len(w.ring)
+		}
+		w.writing = true
+		// 缓冲腾出了空间，唤醒阻塞的写入
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		for _, record := range batch {
+			if _, err := w.out.Write(record.data); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "plog：async writer io write 出现错误， err = %v", err)
+			}
+		}
+
+		w.mu.Lock()
+		w.writing = false
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// Dropped 返回因为缓冲满了被丢弃的日志条数
+func (w *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Flush 等待缓冲里的日志全部写到 out
+func (w *AsyncWriter) Flush() error {
+	w.mu.Lock()
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+	w.mu.Unlock()
+
+	if flusher, ok := w.out.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close 写完缓冲里的日志后停止后台协程，不会关闭 out
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	<-w.done
+	if flusher, ok := w.out.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package plog
+
+import (
+	"context"
+	"time"
+)
+
+// FieldLogger Logger 和 ChildLogger 都实现了，需要注入 logger 的地方使用
+type FieldLogger interface {
+	Named(name string) *ChildLogger
+	With(fields ...Field) *ChildLogger
+	WithContext(ctx context.Context) *Entry
+	WithError(err error) *Entry
+	WithTime(time time.Time) *Entry
+	WithField(key string, value interface{}) *Entry
+	WithFields(fields Fields) *Entry
+	Debug(msg string)
+	Debugf(format string, args ...interface{})
+	Info(msg string)
+	Infof(format string, args ...interface{})
+	Warn(msg string)
+	Warnf(format string, args ...interface{})
+	Error(msg string)
+	Errorf(format string, args ...interface{})
+	Fatal(msg string)
+	Fatalf(format string, args ...interface{})
+}
+
+// ChildLogger Named、With 返回的子 logger，Out、Format、Hooks 和级别都使用创建它的 Logger，
+// 所以不暴露这些字段，需要修改时直接改创建它的 Logger
+type ChildLogger struct {
+	logger *Logger
+}
+
+func (c *ChildLogger) Name() string {
+	return c.logger.name
+}
+
+func (c *ChildLogger) Named(name string) *ChildLogger {
+	return c.logger.Named(name)
+}
+
+func (c *ChildLogger) With(fields ...Field) *ChildLogger {
+	return c.logger.With(fields...)
+}
+
+func (c *ChildLogger) NewContext(ctx context.Context, fields ...Field) context.Context {
+	return c.logger.NewContext(ctx, fields...)
+}
+
+func (c *ChildLogger) GetLevel() LevelType {
+	return c.logger.GetLevel()
+}
+
+func (c *ChildLogger) Flush() error {
+	return c.logger.Flush()
+}
+
+func (c *ChildLogger) WithContext(ctx context.Context) *Entry {
+	return c.logger.WithContext(ctx)
+}
+
+func (c *ChildLogger) WithError(err error) *Entry {
+	return c.logger.WithError(err)
+}
+
+func (c *ChildLogger) WithTime(time time.Time) *Entry {
+	return c.logger.WithTime(time)
+}
+
+func (c *ChildLogger) WithField(key string, value interface{}) *Entry {
+	return c.logger.WithField(key, value)
+}
+
+func (c *ChildLogger) WithFields(fields Fields) *Entry {
+	return c.logger.WithFields(fields)
+}
+
+func (c *ChildLogger) Info(msg string) {
+	c.logger.logf(INFO, msg)
+}
+
+func (c *ChildLogger) Infof(format string, args ...interface{}) {
+	c.logger.logf(INFO, format, args...)
+}
+
+func (c *ChildLogger) Warn(msg string) {
+	c.logger.logf(WARN, msg)
+}
+
+func (c *ChildLogger) Warnf(format string, args ...interface{}) {
+	c.logger.logf(WARN, format, args...)
+}
+
+func (c *ChildLogger) Error(msg string) {
+	c.logger.logf(ERROR, msg)
+}
+
+func (c *ChildLogger) Errorf(format string, args ...interface{}) {
+	c.logger.logf(ERROR, format, args...)
+}
+
+func (c *ChildLogger) Debug(msg string) {
+	c.logger.logf(DEBUG, msg)
+}
+
+func (c *ChildLogger) Debugf(format string, args ...interface{}) {
+	c.logger.logf(DEBUG, format, args...)
+}
+
+func (c *ChildLogger) Fatal(msg string) {
+	c.logger.logf(FATAL, msg)
+	c.logger.exit()
+}
+
+func (c *ChildLogger) Fatalf(format string, args ...interface{}) {
+	c.logger.logf(FATAL, format, args...)
+	c.logger.exit()
+}
@@ -0,0 +1,126 @@
+package plog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	colorRed    = 31
+	colorYellow = 33
+	colorBlue   = 36
+	colorGray   = 37
+)
+
+var LevelColors = map[LevelType]int{
+	FATAL: colorRed,
+	ERROR: colorRed,
+	WARN:  colorYellow,
+	INFO:  colorBlue,
+	DEBUG: colorGray,
+}
+
+// ConsoleFormatter 给人看的格式，带颜色、对齐，时间是相对启动的秒数，输出不是终端时自动去掉颜色
+type ConsoleFormatter struct {
+	ForceColors   bool
+	DisableColors bool
+	// 消息的宽度，字段从这一列开始对齐，默认 44
+	MessageWidth int
+
+	startOnce sync.Once
+	start     time.Time
+
+	mu        sync.Mutex
+	terminals map[*os.File]bool
+}
+
+func (f *ConsoleFormatter) Format(entry *Entry) ([]byte, error) {
+	if entry.Time == nil {
+		now := time.Now()
+		entry.Time = &now
+	}
+
+	if entry.Buffer == nil {
+		entry.Buffer = bufferPool.GetBuffer()
+	}
+
+	f.startOnce.Do(func() {
+		f.start = time.Now()
+	})
+
+	var out io.Writer
+	if entry.logger != nil {
+		base := entry.logger.base()
+		base.Lock()
+		out = base.Out
+		base.Unlock()
+	}
+	colored := f.ForceColors || (!f.DisableColors && f.isTerminal(out))
+	color := LevelColors[entry.Level]
+	width := f.MessageWidth
+	if width <= 0 {
+		width = 44
+	}
+
+	buf := entry.Buffer
+	level := fmt.Sprintf("%-5s", strings.ToUpper(entry.Level.String()))
+	if colored {
+		buf.WriteString(fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, level))
+	} else {
+		buf.WriteString(level)
+	}
+	buf.WriteString(fmt.Sprintf(" [%10.3fs]", entry.Time.Sub(f.start).Seconds()))
+	if entry.CallFrame != nil {
+		file := filepath.Join(filepath.Base(filepath.Dir(entry.CallFrame.File)), filepath.Base(entry.CallFrame.File))
+		buf.WriteString(fmt.Sprintf(" %s:%d", file, entry.CallFrame.Line))
+	}
+
+	if entry.Name != "" {
+		buf.WriteString(fmt.Sprintf(" [%s]", entry.Name))
+	}
+	buf.WriteString(fmt.Sprintf(" %-*s", width, entry.Msg))
+	if entry.Err != nil {
+		f.writeField(buf, colored, color, "err", entry.Err)
+	}
+	for _, field := range entry.Fields {
+		f.writeField(buf, colored, color, field.Key, field.Value)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func (f *ConsoleFormatter) writeField(buf *bytes.Buffer, colored bool, color int, key string, value interface{}) {
+	if colored {
+		buf.WriteString(fmt.Sprintf(" \x1b[%dm%s\x1b[0m=%s", color, key, formatFieldValue(value)))
+		return
+	}
+	buf.WriteString(fmt.Sprintf(" %s=%s", key, formatFieldValue(value)))
+}
+
+// isTerminal 只有 *os.File 并且是字符设备才当作终端，结果按文件缓存，避免每条日志都 stat
+func (f *ConsoleFormatter) isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.terminals == nil {
+		f.terminals = make(map[*os.File]bool)
+	}
+	terminal, ok := f.terminals[file]
+	if !ok {
+		stat, err := file.Stat()
+		terminal = err == nil && stat.Mode()&os.ModeCharDevice != 0
+		f.terminals[file] = terminal
+	}
+	return terminal
+}
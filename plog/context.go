@@ -0,0 +1,164 @@
+package plog
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ContextExtractor 从 context 里取出需要打印的字段
+type ContextExtractor func(ctx context.Context) []Field
+
+type namedExtractor struct {
+	name      string
+	extractor ContextExtractor
+}
+
+var (
+	extractorMu sync.RWMutex
+	extractors  = []namedExtractor{
+		{name: "request_id", extractor: requestIDExtractor},
+		{name: "trace", extractor: traceExtractor},
+		{name: "user_id", extractor: userIDExtractor},
+	}
+)
+
+// RegisterContextExtractor 注册 extractor，同名的会被替换
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+
+	for i := range extractors {
+		if extractors[i].name == name {
+			extractors[i].extractor = extractor
+			return
+		}
+	}
+	extractors = append(extractors, namedExtractor{name: name, extractor: extractor})
+}
+
+func UnregisterContextExtractor(name string) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+
+	for i := range extractors {
+		if extractors[i].name == name {
+			extractors = append(extractors[:i:i], extractors[i+1:]...)
+			return
+		}
+	}
+}
+
+func extractContext(ctx context.Context) []Field {
+	// 复制一份在锁外调用，extractor panic 或者在里面打日志、注册 extractor 都不会死锁
+	extractorMu.RLock()
+	current := make([]namedExtractor, len(extractors))
+	copy(current, extractors)
+	extractorMu.RUnlock()
+
+	var fields []Field
+	for _, ne := range current {
+		fields = append(fields, ne.extractor(ctx)...)
+	}
+	return fields
+}
+
+type (
+	loggerKey      struct{}
+	requestIDKey   struct{}
+	traceParentKey struct{}
+	userIDKey      struct{}
+)
+
+// NewContext 把带有 fields 的 logger 放到 context 里，context 里已经有 logger 时在它的基础上添加
+func NewContext(ctx context.Context, fields ...Field) context.Context {
+	return context.WithValue(ctx, loggerKey{}, loggerFromContext(ctx).with(fields...))
+}
+
+// NewContext 把带有 fields 的子 logger 放到 context 里
+func (logger *Logger) NewContext(ctx context.Context, fields ...Field) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger.with(fields...))
+}
+
+// FromContext 返回 context 里的 logger 生成的 entry，没有时使用 root logger
+func FromContext(ctx context.Context) *Entry {
+	return loggerFromContext(ctx).WithContext(ctx)
+}
+
+func loggerFromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return logger
+	}
+	return root
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// ContextWithTraceParent traceparent 为 W3C Trace Context 的 header，比如 00-{trace id}-{span id}-01
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+func ContextWithUserID(ctx context.Context, userID interface{}) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func requestIDExtractor(ctx context.Context) []Field {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok && requestID != "" {
+		return []Field{{Key: "request_id", Value: requestID}}
+	}
+	return nil
+}
+
+func userIDExtractor(ctx context.Context) []Field {
+	if userID := ctx.Value(userIDKey{}); userID != nil {
+		return []Field{{Key: "user_id", Value: userID}}
+	}
+	return nil
+}
+
+func traceExtractor(ctx context.Context) []Field {
+	traceParent, ok := ctx.Value(traceParentKey{}).(string)
+	if !ok {
+		return nil
+	}
+	traceID, spanID, ok := ParseTraceParent(traceParent)
+	if !ok {
+		return nil
+	}
+	return []Field{{Key: "trace_id", Value: traceID}, {Key: "span_id", Value: spanID}}
+}
+
+// ParseTraceParent 解析 W3C traceparent，格式不对或者 id 全为 0 时返回 false
+func ParseTraceParent(traceParent string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 {
+		return "", "", false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	// 00 版本只有四段，ff 是非法版本
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	if !isLowerHex(traceID, 32) || !isLowerHex(spanID, 16) || !isLowerHex(flags, 2) {
+		return "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
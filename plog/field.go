@@ -0,0 +1,79 @@
+package plog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+type Fields map[string]interface{}
+
+// appendFields 返回新的切片，不修改原来的，相同的 key 用新值覆盖
+func appendFields(fields []Field, added ...Field) []Field {
+	result := make([]Field, len(fields), len(fields)+len(added))
+	copy(result, fields)
+
+	for _, field := range added {
+		replaced := false
+		for i := range result {
+			if result[i].Key == field.Key {
+				result[i].Value = field.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// sortedFields map 没有顺序，按 key 排序，避免每次输出都不一样
+func sortedFields(fields Fields) []Field {
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]Field, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, Field{Key: key, Value: fields[key]})
+	}
+	return list
+}
+
+func formatFieldValue(value interface{}) string {
+	var s string
+	if v, ok := value.(string); ok {
+		s = v
+	} else {
+		// fmt 会调用 Error、String，值为 nil 的指针和方法里的 panic 也会处理，不会让打日志 panic
+		s = fmt.Sprint(value)
+	}
+
+	if s == "" || needsQuote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// needsQuote 空格、等号、引号、控制字符和换行类的字符需要加引号转义，否则会破坏一行一条的格式
+func needsQuote(s string) bool {
+	for _, r := range s {
+		switch {
+		case r < 0x20, r == 0x7f, r == ' ', r == '=', r == '"':
+			return true
+		case r == utf8.RuneError, r == '\u0085', r == '\u2028', r == '\u2029':
+			return true
+		}
+	}
+	return false
+}
@@ -41,9 +41,15 @@ func (f *DefaultFormatter) Format(entry *Entry) ([]byte, error) {
 		entry.Buffer.WriteString(fmt.Sprintf(" %s.%d", entry.CallFrame.File, entry.CallFrame.Line))
 	}
 	entry.Buffer.WriteString(fmt.Sprintf("]"))
+	if entry.Name != "" {
+		entry.Buffer.WriteString(fmt.Sprintf(" [%s]", entry.Name))
+	}
 	entry.Buffer.WriteString(fmt.Sprintf(" %s", entry.Msg))
+	for _, field := range entry.Fields {
+		entry.Buffer.WriteString(fmt.Sprintf(" %s=%s", field.Key, formatFieldValue(field.Value)))
+	}
 	if entry.Err != nil {
-		entry.Buffer.WriteString(fmt.Sprintf(", err = %s", entry.Err.Error()))
+		entry.Buffer.WriteString(fmt.Sprintf(", err = %v", entry.Err))
 	}
 	entry.Buffer.WriteByte('\n')
 
@@ -0,0 +1,261 @@
+package plog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultLevelKey   = "level"
+	DefaultTimeKey    = "time"
+	DefaultCallerKey  = "caller"
+	DefaultMessageKey = "msg"
+	DefaultErrorKey   = "error"
+	DefaultNameKey    = "logger"
+)
+
+// JSONFormatter 每条日志输出一行 json，key 为空时使用默认值，字段和固定的 key 重名时加上 fields. 前缀
+type JSONFormatter struct {
+	LevelKey   string
+	TimeKey    string
+	CallerKey  string
+	MessageKey string
+	ErrorKey   string
+	NameKey    string
+	// 默认 time.RFC3339Nano
+	TimeFormat string
+}
+
+func keyOrDefault(key, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+	return key
+}
+
+func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	if entry.Time == nil {
+		now := time.Now()
+		entry.Time = &now
+	}
+
+	if entry.Buffer == nil {
+		entry.Buffer = bufferPool.GetBuffer()
+	}
+
+	levelKey := keyOrDefault(f.LevelKey, DefaultLevelKey)
+	timeKey := keyOrDefault(f.TimeKey, DefaultTimeKey)
+	callerKey := keyOrDefault(f.CallerKey, DefaultCallerKey)
+	messageKey := keyOrDefault(f.MessageKey, DefaultMessageKey)
+	errorKey := keyOrDefault(f.ErrorKey, DefaultErrorKey)
+	nameKey := keyOrDefault(f.NameKey, DefaultNameKey)
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	buf := entry.Buffer
+	buf.WriteByte('{')
+	writeJSONString(buf, levelKey)
+	buf.WriteByte(':')
+	writeJSONString(buf, entry.Level.String())
+
+	buf.WriteByte(',')
+	writeJSONString(buf, timeKey)
+	buf.WriteByte(':')
+	writeJSONString(buf, entry.Time.Format(timeFormat))
+
+	if entry.CallFrame != nil {
+		buf.WriteByte(',')
+		writeJSONString(buf, callerKey)
+		buf.WriteString(`:"`)
+		writeJSONEscaped(buf, entry.CallFrame.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(entry.CallFrame.Line))
+		buf.WriteByte('"')
+	}
+
+	if entry.Name != "" {
+		buf.WriteByte(',')
+		writeJSONString(buf, nameKey)
+		buf.WriteByte(':')
+		writeJSONString(buf, entry.Name)
+	}
+
+	buf.WriteByte(',')
+	writeJSONString(buf, messageKey)
+	buf.WriteByte(':')
+	writeJSONString(buf, entry.Msg)
+
+	if entry.Err != nil {
+		buf.WriteByte(',')
+		writeJSONString(buf, errorKey)
+		buf.WriteByte(':')
+		writeJSONString(buf, fmt.Sprint(entry.Err))
+	}
+
+	for _, field := range entry.Fields {
+		buf.WriteByte(',')
+		key := field.Key
+		if key == levelKey || key == timeKey || key == callerKey || key == messageKey || key == errorKey || key == nameKey {
+			buf.WriteString(`"fields.`)
+			writeJSONEscaped(buf, key)
+			buf.WriteByte('"')
+		} else {
+			writeJSONString(buf, key)
+		}
+		buf.WriteByte(':')
+		// 序列化失败时不丢掉整行，退回成 fmt.Sprint 的字符串
+		size := buf.Len()
+		if err := writeJSONValue(buf, field.Value); err != nil {
+			buf.Truncate(size)
+			writeJSONString(buf, fmt.Sprint(field.Value))
+		}
+	}
+	buf.WriteString("}\n")
+
+	return buf.Bytes(), nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	// 值为 nil 的指针调用 MarshalJSON、Error、String 可能会 panic
+	if isNilPointer(value) {
+		buf.WriteString("null")
+		return nil
+	}
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int8:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int16:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int32:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float32:
+		writeJSONFloat(buf, float64(v), 32)
+	case float64:
+		writeJSONFloat(buf, v, 64)
+	case time.Time:
+		writeJSONString(buf, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		writeJSONString(buf, v.String())
+	case error:
+		writeJSONString(buf, v.Error())
+	case json.Marshaler:
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		// 自定义的 MarshalJSON 可能带换行，压缩成一行
+		return json.Compact(buf, data)
+	case fmt.Stringer:
+		writeJSONString(buf, v.String())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+func isNilPointer(value interface{}) bool {
+	vv := reflect.ValueOf(value)
+	return vv.Kind() == reflect.Pointer && vv.IsNil()
+}
+
+// writeJSONFloat json 不支持 NaN 和 Inf，输出成字符串
+func writeJSONFloat(buf *bytes.Buffer, v float64, bitSize int) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		writeJSONString(buf, strconv.FormatFloat(v, 'g', -1, bitSize))
+		return
+	}
+	buf.WriteString(strconv.FormatFloat(v, 'g', -1, bitSize))
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	writeJSONEscaped(buf, s)
+	buf.WriteByte('"')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONEscaped 和 encoding/json 一样转义，非法的 utf8 替换成 \ufffd
+func writeJSONEscaped(buf *bytes.Buffer, s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 和 U+2029 在 javascript 里是换行
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+}
@@ -0,0 +1,246 @@
+package plog
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LevelsEnvName = "plog.levels"
+	noOverride    = LevelType(-1)
+)
+
+// ParseLevels 解析 conn_pool=debug,psql=warn 形式的配置
+func ParseLevels(spec string) (map[string]LevelType, error) {
+	levels := make(map[string]LevelType)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("plog: level item %q lack of =", item)
+		}
+		key := strings.Trim(strings.TrimSpace(kv[0]), "/")
+		levelType, ok := LevelTypeMap[strings.ToLower(strings.TrimSpace(kv[1]))]
+		if key == "" || !ok {
+			return nil, fmt.Errorf("plog: level item %q is invalid", item)
+		}
+		levels[key] = levelType
+	}
+	return levels, nil
+}
+
+type levelRule struct {
+	key   string
+	level LevelType
+}
+
+type levelOverrides struct {
+	rules    []levelRule
+	min, max LevelType
+	// 包名到级别的缓存，没有匹配的规则时为 noOverride
+	cache sync.Map
+}
+
+func newLevelOverrides(levels map[string]LevelType) *levelOverrides {
+	lo := &levelOverrides{min: DEBUG, max: FATAL}
+	for key, level := range levels {
+		lo.rules = append(lo.rules, levelRule{key: key, level: level})
+		if level < lo.min {
+			lo.min = level
+		}
+		if level > lo.max {
+			lo.max = level
+		}
+	}
+	// key 越长越具体，优先匹配
+	sort.Slice(lo.rules, func(i, j int) bool {
+		if len(lo.rules[i].key) != len(lo.rules[j].key) {
+			return len(lo.rules[i].key) > len(lo.rules[j].key)
+		}
+		return lo.rules[i].key < lo.rules[j].key
+	})
+	return lo
+}
+
+// levelPath 子 logger 的名字用 . 分隔，和包路径一样按段匹配
+func levelPath(name string) string {
+	return strings.ReplaceAll(name, ".", "/")
+}
+
+// match key 按路径段匹配，psql 能匹配 github.com/yongpi/putil/psql 和它的子包
+func (lo *levelOverrides) match(name string) LevelType {
+	if level, ok := lo.cache.Load(name); ok {
+		return level.(LevelType)
+	}
+
+	level := noOverride
+	path := "/" + levelPath(name) + "/"
+	for _, rule := range lo.rules {
+		if strings.Contains(path, "/"+levelPath(rule.key)+"/") {
+			level = rule.level
+			break
+		}
+	}
+	lo.cache.Store(name, level)
+	return level
+}
+
+func (logger *Logger) GetLevel() LevelType {
+	return LevelType(logger.base().level.Load())
+}
+
+// SetLevel 运行时修改级别，会取消 SetLevelFor 还没到期的临时级别
+func (logger *Logger) SetLevel(levelType LevelType) {
+	logger = logger.base()
+	logger.Lock()
+	defer logger.Unlock()
+
+	logger.setLevel(levelType)
+	if logger.levelTimer != nil {
+		logger.levelTimer.Stop()
+		logger.levelTimer = nil
+	}
+}
+
+// SetLevelFor 临时修改级别，duration 之后恢复成修改之前的级别
+func (logger *Logger) SetLevelFor(levelType LevelType, duration time.Duration) {
+	logger = logger.base()
+	logger.Lock()
+	defer logger.Unlock()
+
+	// 上一个临时级别还没到期时，恢复的仍然是最开始的级别
+	if logger.levelTimer != nil {
+		logger.levelTimer.Stop()
+	} else {
+		logger.revertLevel = logger.GetLevel()
+	}
+	logger.setLevel(levelType)
+
+	gen := logger.levelGen
+	logger.levelTimer = time.AfterFunc(duration, func() {
+		logger.Lock()
+		defer logger.Unlock()
+		// 期间被 SetLevel 修改过就不再恢复
+		if logger.levelGen == gen {
+			logger.setLevel(logger.revertLevel)
+			logger.levelTimer = nil
+		}
+	})
+}
+
+// stepLevel verbose 为 true 时调到更详细的一级，最多到 DEBUG，否则调低一级，最少到 FATAL
+func (logger *Logger) stepLevel(verbose bool) {
+	level := logger.GetLevel()
+	if verbose && level < DEBUG {
+		level++
+	} else if !verbose && level > FATAL {
+		level--
+	}
+	logger.SetLevel(level)
+}
+
+// setLevel 调用方持有锁，和 SetLevelFor 的恢复互斥，读取级别不需要锁
+func (logger *Logger) setLevel(levelType LevelType) {
+	logger.level.Store(int32(levelType))
+	logger.levelGen++
+}
+
+// SetLevels 按包名或者子 logger 名覆盖日志级别，传 nil 清空
+func (logger *Logger) SetLevels(levels map[string]LevelType) {
+	logger = logger.base()
+	if len(levels) == 0 {
+		logger.levels.Store(nil)
+		return
+	}
+	logger.levels.Store(newLevelOverrides(levels))
+}
+
+func (logger *Logger) SetLevelsString(spec string) error {
+	levels, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	logger.SetLevels(levels)
+	return nil
+}
+
+func (logger *Logger) enabled(levelType LevelType) bool {
+	return logger.enabledFor(levelType, callerPackage)
+}
+
+// enabledFor caller 返回调用方的包名，只有需要按包名判断时才调用
+func (logger *Logger) enabledFor(levelType LevelType, caller func() string) bool {
+	level := logger.GetLevel()
+	lo := logger.base().levels.Load()
+	if lo == nil {
+		return levelType <= level
+	}
+
+	// 所有级别都打开或者都关闭时，不需要找调用方
+	if levelType <= level && levelType <= lo.min {
+		return true
+	}
+	if levelType > level && levelType > lo.max {
+		return false
+	}
+
+	// 子 logger 的名字优先，没有匹配的再按调用方的包名
+	override := noOverride
+	if logger.name != "" {
+		override = lo.match(logger.name)
+	}
+	if override == noOverride {
+		override = lo.match(caller())
+	}
+	if override != noOverride {
+		level = override
+	}
+	return levelType <= level
+}
+
+// pcPackages pc 到包名的缓存，pc 展开的内联帧全部是 plog 时为 packageName
+var pcPackages sync.Map
+
+// callerPackage 返回第一个不是 plog 的调用方的包名，同一个调用位置只解析一次调用帧
+func callerPackage() string {
+	packageInitOnce.Do(initPackage)
+
+	var pcs [maxSkip]uintptr
+	n := runtime.Callers(minSkip-1, pcs[:])
+	for _, pc := range pcs[:n] {
+		if name := pcPackage(pc); name != packageName {
+			return name
+		}
+	}
+	return ""
+}
+
+func pcPackage(pc uintptr) string {
+	if name, ok := pcPackages.Load(pc); ok {
+		return name.(string)
+	}
+
+	// 一个 pc 可能对应多个内联的函数，取第一个不是 plog 的
+	name := packageName
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if pn := getPackageName(frame.Function); pn != packageName {
+			name = pn
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	pcPackages.Store(pc, name)
+	return name
+}
@@ -0,0 +1,72 @@
+package plog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type levelRequest struct {
+	Level string `json:"level"`
+	// 不为空时是临时级别，比如 5m
+	Duration string `json:"duration,omitempty"`
+}
+
+type levelResponse struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// LevelHandler GET 返回当前级别，PUT {"level": "debug", "duration": "5m"} 修改级别
+func (logger *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := logger.putLevel(r); err != nil {
+				writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: err.Error()})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelResponse(w, http.StatusMethodNotAllowed, levelResponse{Error: fmt.Sprintf("method %s not allowed", r.Method)})
+			return
+		}
+		writeLevelResponse(w, http.StatusOK, levelResponse{Level: logger.GetLevel().String()})
+	})
+}
+
+func (logger *Logger) putLevel(r *http.Request) error {
+	var req levelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return fmt.Errorf("decode request fail, err = %w", err)
+	}
+
+	levelType, ok := LevelTypeMap[strings.ToLower(req.Level)]
+	if !ok {
+		return fmt.Errorf("level %q is invalid", req.Level)
+	}
+	if req.Duration == "" {
+		logger.SetLevel(levelType)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("duration %q is invalid", req.Duration)
+	}
+	logger.SetLevelFor(levelType, duration)
+	return nil
+}
+
+func writeLevelResponse(w http.ResponseWriter, code int, resp levelResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func LevelHandler() http.Handler {
+	return root.LevelHandler()
+}
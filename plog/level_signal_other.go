@@ -0,0 +1,12 @@
+//go:build !aix && !android && !darwin && !dragonfly && !freebsd && !hurd && !illumos && !ios && !linux && !netbsd && !openbsd && !solaris
+
+package plog
+
+// LevelOnSignal 没有 SIGUSR1 和 SIGUSR2 的平台不做任何事情
+func (logger *Logger) LevelOnSignal() func() {
+	return func() {}
+}
+
+func LevelOnSignal() func() {
+	return root.LevelOnSignal()
+}
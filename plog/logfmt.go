@@ -0,0 +1,71 @@
+package plog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogfmtFormatter 输出 level=info ts=... caller=... msg="..." k=v 这样的格式
+type LogfmtFormatter struct {
+	// 默认 time.RFC3339Nano
+	TimeFormat string
+}
+
+func (f *LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	if entry.Time == nil {
+		now := time.Now()
+		entry.Time = &now
+	}
+
+	if entry.Buffer == nil {
+		entry.Buffer = bufferPool.GetBuffer()
+	}
+
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	buf := entry.Buffer
+	buf.WriteString("level=")
+	buf.WriteString(entry.Level.String())
+	buf.WriteString(" ts=")
+	buf.WriteString(formatFieldValue(entry.Time.Format(timeFormat)))
+	if entry.CallFrame != nil {
+		buf.WriteString(" caller=")
+		buf.WriteString(formatFieldValue(fmt.Sprintf("%s:%d", entry.CallFrame.File, entry.CallFrame.Line)))
+	}
+	if entry.Name != "" {
+		buf.WriteString(" logger=")
+		buf.WriteString(formatFieldValue(entry.Name))
+	}
+	buf.WriteString(" msg=")
+	buf.WriteString(formatFieldValue(entry.Msg))
+	if entry.Err != nil {
+		buf.WriteString(" err=")
+		buf.WriteString(formatFieldValue(entry.Err))
+	}
+	for _, field := range entry.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(logfmtKey(field.Key))
+		buf.WriteByte('=')
+		buf.WriteString(formatFieldValue(field.Value))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// logfmtKey key 里不能有空格、等号、引号
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
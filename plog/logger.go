@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -48,8 +49,8 @@ var (
 )
 
 const (
-	minSkip = 4
-	maxSkip = 25
+	minSkip          = 4
+	maxSkip          = 25
 	LevelTypeEnvName = "plog.level_type"
 )
 
@@ -67,33 +68,81 @@ func init() {
 	}}
 
 	root = NewLogger(LevelTypeFromString(os.Getenv(LevelTypeEnvName)))
+	if spec := os.Getenv(LevelsEnvName); spec != "" {
+		if err := root.SetLevelsString(spec); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "plog：%s 配置错误， err = %v", LevelsEnvName, err)
+		}
+	}
 }
 
 type Logger struct {
 	sync.Mutex
 	Out        io.Writer
-	Level      LevelType
 	IsCaller   bool
 	Format     Formatter
 	Hooks      Hooks
 	AutoCaller bool
+	// 级别通过 GetLevel、SetLevel 读写，判断级别时不加锁
+	level  atomic.Int32
+	levels atomic.Pointer[levelOverrides]
+
+	levelGen    uint64
+	levelTimer  *time.Timer
+	revertLevel LevelType
+
+	// 内部的子 logger 使用 parent 的 Out、Format、Hooks 和级别，对外通过 ChildLogger 使用
+	parent *Logger
+	name   string
+	fields []Field
 }
 
 func NewLogger(levelType LevelType) *Logger {
-	return &Logger{
+	logger := &Logger{
 		Out:        os.Stderr,
-		Level:      levelType,
 		Format:     new(DefaultFormatter),
 		AutoCaller: true,
 	}
+	logger.level.Store(int32(levelType))
+	return logger
 }
 
 func (logger *Logger) newEntry() *Entry {
 	entry := entryPool.GetEntry()
 	entry.logger = logger
+	entry.Name = logger.name
+	entry.Fields = logger.fields
 	return entry
 }
 
+// base 子 logger 返回最顶层的 logger
+func (logger *Logger) base() *Logger {
+	if logger.parent != nil {
+		return logger.parent
+	}
+	return logger
+}
+
+// Named 返回子 logger，多次 Named 的名字用 . 连接
+func (logger *Logger) Named(name string) *ChildLogger {
+	if logger.name != "" {
+		name = logger.name + "." + name
+	}
+	return &ChildLogger{logger: &Logger{parent: logger.base(), name: name, fields: logger.fields}}
+}
+
+// With 返回带有预设字段的子 logger
+func (logger *Logger) With(fields ...Field) *ChildLogger {
+	return &ChildLogger{logger: logger.with(fields...)}
+}
+
+func (logger *Logger) with(fields ...Field) *Logger {
+	return &Logger{parent: logger.base(), name: logger.name, fields: appendFields(logger.fields, fields...)}
+}
+
+func (logger *Logger) Name() string {
+	return logger.name
+}
+
 func (logger *Logger) WithContext(ctx context.Context) *Entry {
 	entry := logger.newEntry()
 	defer entryPool.PutEntry(entry)
@@ -112,8 +161,20 @@ func (logger *Logger) WithTime(time time.Time) *Entry {
 	return entry.WithTime(time)
 }
 
+func (logger *Logger) WithField(key string, value interface{}) *Entry {
+	entry := logger.newEntry()
+	defer entryPool.PutEntry(entry)
+	return entry.WithField(key, value)
+}
+
+func (logger *Logger) WithFields(fields Fields) *Entry {
+	entry := logger.newEntry()
+	defer entryPool.PutEntry(entry)
+	return entry.WithFields(fields)
+}
+
 func (logger *Logger) logf(levelType LevelType, format string, args ...interface{}) {
-	if levelType > logger.Level {
+	if !logger.enabled(levelType) {
 		return
 	}
 	entry := logger.newEntry()
@@ -155,17 +216,37 @@ func (logger *Logger) Debugf(format string, args ...interface{}) {
 
 func (logger *Logger) Fatal(msg string) {
 	logger.logf(FATAL, msg)
-	os.Exit(1)
-
+	logger.exit()
 }
 
 func (logger *Logger) Fatalf(format string, args ...interface{}) {
 	logger.logf(FATAL, format, args...)
+	logger.exit()
+}
+
+// Flush Out 实现了 Flusher 时，等待缓冲的日志写完
+func (logger *Logger) Flush() error {
+	base := logger.base()
+	base.Lock()
+	out := base.Out
+	base.Unlock()
+
+	if flusher, ok := out.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (logger *Logger) exit() {
+	if err := logger.Flush(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "plog：logger flush 出现错误， err = %v", err)
+	}
 	os.Exit(1)
 }
 
 type Entry struct {
 	logger    *Logger
+	Name      string
 	Level     LevelType
 	Context   context.Context
 	Err       error
@@ -173,18 +254,37 @@ type Entry struct {
 	Time      *time.Time
 	Msg       string
 	Buffer    *bytes.Buffer
+	Fields    []Field
 }
 
 func (e *Entry) WithContext(ctx context.Context) *Entry {
-	return &Entry{logger: e.logger, Context: ctx, Err: e.Err, Time: e.Time}
+	return &Entry{logger: e.logger, Name: e.Name, Context: ctx, Err: e.Err, Time: e.Time, Fields: e.Fields}
 }
 
 func (e *Entry) WithError(err error) *Entry {
-	return &Entry{logger: e.logger, Context: e.Context, Err: err, Time: e.Time}
+	return &Entry{logger: e.logger, Name: e.Name, Context: e.Context, Err: err, Time: e.Time, Fields: e.Fields}
 }
 
 func (e *Entry) WithTime(time time.Time) *Entry {
-	return &Entry{logger: e.logger, Context: e.Context, Err: e.Err, Time: &time}
+	return &Entry{logger: e.logger, Name: e.Name, Context: e.Context, Err: e.Err, Time: &time, Fields: e.Fields}
+}
+
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return &Entry{logger: e.logger, Name: e.Name, Context: e.Context, Err: e.Err, Time: e.Time, Fields: appendFields(e.Fields, Field{Key: key, Value: value})}
+}
+
+func (e *Entry) WithFields(fields Fields) *Entry {
+	return &Entry{logger: e.logger, Name: e.Name, Context: e.Context, Err: e.Err, Time: e.Time, Fields: appendFields(e.Fields, sortedFields(fields)...)}
+}
+
+// Field 钩子里可以用来读取字段
+func (e *Entry) Field(key string) (interface{}, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
 }
 
 func (e *Entry) Info(msg string) {
@@ -221,28 +321,34 @@ func (e *Entry) Debugf(format string, args ...interface{}) {
 
 func (e *Entry) Fatal(msg string) {
 	e.Logf(FATAL, msg)
-	os.Exit(1)
+	e.logger.exit()
 }
 
 func (e *Entry) Fatalf(format string, args ...interface{}) {
 	e.Logf(FATAL, format, args...)
-	os.Exit(1)
+	e.logger.exit()
 }
 
 func (e *Entry) Release() {
 	e.logger = nil
+	e.Name = ""
 	e.Context = nil
 	e.Time = nil
 	e.CallFrame = nil
 	e.Err = nil
 	e.Buffer = nil
+	e.Fields = nil
 }
 
 func (e *Entry) Logf(levelType LevelType, format string, args ...interface{}) {
-	if e.logger.Level < levelType {
+	if !e.logger.enabled(levelType) {
 		return
 	}
+	e.log(levelType, fmt.Sprintf(format, args...), getCaller)
+}
 
+// log 复制 entry 并写日志，需要调用帧时才调用 caller
+func (e *Entry) log(levelType LevelType, msg string, caller func() *runtime.Frame) {
 	// 复制到新的 entry
 	newEntry := *e
 	if newEntry.Time == nil {
@@ -251,11 +357,13 @@ func (e *Entry) Logf(levelType LevelType, format string, args ...interface{}) {
 	}
 	newEntry.Level = levelType
 
-	// 因为 logger 是共享的， 所以需要上锁获取 logger 的 IsCaller 和 AutoCaller 属性
-	newEntry.logger.Lock()
-	isCaller := newEntry.logger.IsCaller
-	autoCaller := newEntry.logger.AutoCaller
-	newEntry.logger.Unlock()
+	// 因为 logger 是共享的， 所以需要上锁获取 logger 的 IsCaller、AutoCaller 和 Out 属性
+	base := newEntry.logger.base()
+	base.Lock()
+	isCaller := base.IsCaller
+	autoCaller := base.AutoCaller
+	out := base.Out
+	base.Unlock()
 
 	// AutoCaller 逻辑
 	if autoCaller && levelType <= ERROR {
@@ -264,12 +372,19 @@ func (e *Entry) Logf(levelType LevelType, format string, args ...interface{}) {
 
 	if isCaller {
 		// 获取调用函数的栈帧
-		newEntry.CallFrame = getCaller()
+		newEntry.CallFrame = caller()
+	}
+	newEntry.Msg = msg
+
+	// context 里取出的字段放在前面，同名时以 entry 上的为准
+	if newEntry.Context != nil {
+		if fields := extractContext(newEntry.Context); len(fields) > 0 {
+			newEntry.Fields = appendFields(fields, newEntry.Fields...)
+		}
 	}
-	newEntry.Msg = fmt.Sprintf(format, args...)
 
 	// 执行钩子
-	newEntry.logger.Hooks.HookOn(&newEntry)
+	base.Hooks.HookOn(&newEntry)
 
 	// 获取 buffer
 	buffer := bufferPool.GetBuffer()
@@ -279,39 +394,58 @@ func (e *Entry) Logf(levelType LevelType, format string, args ...interface{}) {
 
 	newEntry.Buffer = buffer
 	// 写日志
-	newEntry.write()
+	newEntry.write(base, out)
 }
 
-func (e *Entry) write() {
-	lc, err := e.logger.Format.Format(e)
+func (e *Entry) write(base *Logger, out io.Writer) {
+	// EntryWriter 自己处理并发，在锁外调用，handler 里再打日志也不会死锁
+	if ew, ok := out.(EntryWriter); ok {
+		if err := ew.WriteEntry(e); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "plog：logger entry write 出现错误， err = %v", err)
+		}
+		return
+	}
+
+	lc, err := base.Format.Format(e)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "plog：logger format 出现错误， err = %v", err)
 		return
 	}
-	e.logger.Lock()
-	defer e.logger.Unlock()
+	base.Lock()
+	defer base.Unlock()
 	// 写入需要加锁
-	if _, err := e.logger.Out.Write(lc); err != nil {
+	if lw, ok := out.(LevelWriter); ok {
+		_, err = lw.WriteLevel(e.Level, lc)
+	} else {
+		_, err = out.Write(lc)
+	}
+	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "plog：logger io write 出现错误， err = %v", err)
 		return
 	}
 }
 
+// initPackage 初始化一次，获取当前包的包名
+func initPackage() {
+	pcs := make([]uintptr, 2)
+	// 从 runtime.Callers 开始获取栈，两层就够了；runtime.Callers 可能被内联，用 CallersFrames 展开
+	n := runtime.Callers(0, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for frame, more := frames.Next(); ; frame, more = frames.Next() {
+		if strings.Contains(frame.Function, "initPackage") {
+			packageName = getPackageName(frame.Function)
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	packageSkip = minSkip
+}
+
 func getCaller() *runtime.Frame {
 	// 初始化一次，获取当前包的包名
-	packageInitOnce.Do(func() {
-		pcs := make([]uintptr, 2)
-		// 从 runtime.Callers 开始获取栈，两层就够了
-		runtime.Callers(0, pcs)
-		for i := 0; i < 2; i++ {
-			funcName := runtime.FuncForPC(pcs[i]).Name()
-			if strings.Contains(funcName, "getCaller") {
-				packageName = getPackageName(funcName)
-				break
-			}
-		}
-		packageSkip = minSkip
-	})
+	packageInitOnce.Do(initPackage)
 
 	pcs := make([]uintptr, maxSkip)
 	// 从第四层调用开始，最大 25 层
@@ -359,6 +493,18 @@ func WithTime(time time.Time) *Entry {
 	return entry.WithTime(time)
 }
 
+func WithField(key string, value interface{}) *Entry {
+	entry := root.newEntry()
+	defer entryPool.PutEntry(entry)
+	return entry.WithField(key, value)
+}
+
+func WithFields(fields Fields) *Entry {
+	entry := root.newEntry()
+	defer entryPool.PutEntry(entry)
+	return entry.WithFields(fields)
+}
+
 func Info(msg string) {
 	root.logf(INFO, msg)
 }
@@ -393,13 +539,44 @@ func Debugf(format string, args ...interface{}) {
 
 func Fatal(msg string) {
 	root.logf(FATAL, msg)
-	os.Exit(1)
-
+	root.exit()
 }
 
 func Fatalf(format string, args ...interface{}) {
 	root.logf(FATAL, format, args...)
-	os.Exit(1)
+	root.exit()
+}
+
+func Flush() error {
+	return root.Flush()
+}
+
+func Named(name string) *ChildLogger {
+	return root.Named(name)
+}
+
+func With(fields ...Field) *ChildLogger {
+	return root.With(fields...)
+}
+
+func GetLevel() LevelType {
+	return root.GetLevel()
+}
+
+func SetLevel(levelType LevelType) {
+	root.SetLevel(levelType)
+}
+
+func SetLevelFor(levelType LevelType, duration time.Duration) {
+	root.SetLevelFor(levelType, duration)
+}
+
+func SetLevels(levels map[string]LevelType) {
+	root.SetLevels(levels)
+}
+
+func SetLevelsString(spec string) error {
+	return root.SetLevelsString(spec)
 }
 
 func InjectHook(levelType LevelType, hook Hook) {
@@ -18,6 +18,22 @@ var LevelTypeMap = map[string]LevelType{
 	"debug": DEBUG,
 }
 
+func (lt LevelType) String() string {
+	switch lt {
+	case FATAL:
+		return "fatal"
+	case ERROR:
+		return "error"
+	case WARN:
+		return "warn"
+	case INFO:
+		return "info"
+	case DEBUG:
+		return "debug"
+	}
+	return ""
+}
+
 func LevelTypeFromString(levelTypeStr string) LevelType {
 	levelType, ok := LevelTypeMap[levelTypeStr]
 	if !ok {
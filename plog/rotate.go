@@ -0,0 +1,347 @@
+package plog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RotatePeriod int
+
+const (
+	RotateNever RotatePeriod = iota
+	RotateHourly
+	RotateDaily
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type RotateConfig struct {
+	maxSize    int64
+	period     RotatePeriod
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	now        func() time.Time
+}
+
+type RotateOption func(cfg *RotateConfig)
+
+// MaxSize 文件超过多少字节时切分，0 表示不按大小切分
+func MaxSize(maxSize int64) RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.maxSize = maxSize
+	}
+}
+
+func RotateEvery(period RotatePeriod) RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.period = period
+	}
+}
+
+// MaxBackups 最多保留多少个切分出来的文件，0 表示不限制
+func MaxBackups(maxBackups int) RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.maxBackups = maxBackups
+	}
+}
+
+// MaxAge 切分出来的文件最多保留多久，0 表示不限制
+func MaxAge(maxAge time.Duration) RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.maxAge = maxAge
+	}
+}
+
+// Compress 切分出来的文件用 gzip 压缩
+func Compress() RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.compress = true
+	}
+}
+
+// RotateClock 替换获取当前时间的函数，测试用
+func RotateClock(now func() time.Time) RotateOption {
+	return func(cfg *RotateConfig) {
+		cfg.now = now
+	}
+}
+
+// RotateWriter 按大小、按天或者按小时切分的文件，切分在写锁里完成，不会丢日志
+type RotateWriter struct {
+	sync.Mutex
+	filename    string
+	cfg         *RotateConfig
+	file        *os.File
+	size        int64
+	periodStart time.Time
+	wg          sync.WaitGroup
+}
+
+func NewRotateWriter(filename string, options ...RotateOption) (*RotateWriter, error) {
+	cfg := &RotateConfig{now: time.Now}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	w := &RotateWriter{filename: filename, cfg: cfg}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := w.cfg.now()
+	needRotate := w.cfg.period != RotateNever && !w.periodOf(now).Equal(w.periodStart)
+	if w.cfg.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.cfg.maxSize {
+		needRotate = true
+	}
+	if needRotate {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotateWriter) Rotate() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.rotate(w.cfg.now())
+}
+
+// Reopen 文件被外部的 logrotate 移走之后，重新打开同名文件
+func (w *RotateWriter) Reopen() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Close 关闭文件，并且等待压缩完成
+func (w *RotateWriter) Close() error {
+	w.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *RotateWriter) periodOf(t time.Time) time.Time {
+	switch w.cfg.period {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = stat.Size()
+	// 已有的文件按修改时间算周期，上个周期写的文件下次写入时就会切分
+	if w.size > 0 {
+		w.periodStart = w.periodOf(stat.ModTime())
+	} else {
+		w.periodStart = w.periodOf(w.cfg.now())
+	}
+	return nil
+}
+
+// backupName 同一毫秒内切分多次时加上序号，比如 app-2026-01-01T00-00-00.000.1.log，避免覆盖已有的文件
+func (w *RotateWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(w.filename, ext)
+	name := fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext)
+	for seq := 1; backupExists(name); seq++ {
+		name = fmt.Sprintf("%s-%s.%d%s", prefix, t.Format(backupTimeFormat), seq, ext)
+	}
+	return name
+}
+
+func backupExists(name string) bool {
+	for _, path := range []string{name, name + ".gz"} {
+		if _, err := os.Stat(path); err == nil || !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// rotate now 在锁里取好，后台的清理不再调用时钟
+func (w *RotateWriter) rotate(now time.Time) error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	backup := w.backupName(now)
+	if err := os.Rename(w.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	// 压缩和清理放到后台，不阻塞写日志
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if w.cfg.compress {
+			if err := compressFile(backup); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "plog：rotate writer compress 出现错误， err = %v", err)
+			}
+		}
+		if err := w.cleanup(now); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "plog：rotate writer cleanup 出现错误， err = %v", err)
+		}
+	}()
+	return nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// parseBackupTime 解析文件名里的时间和可能带的序号
+func parseBackupTime(ts string) (time.Time, int, bool) {
+	if len(ts) < len(backupTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, ts[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	rest := ts[len(backupTimeFormat):]
+	if rest == "" {
+		return t, 0, true
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(rest, "."))
+	if !strings.HasPrefix(rest, ".") || err != nil || seq <= 0 {
+		return time.Time{}, 0, false
+	}
+	return t, seq, true
+}
+
+// cleanup 按 MaxBackups 和 MaxAge 删除旧的文件
+func (w *RotateWriter) cleanup(now time.Time) error {
+	if w.cfg.maxBackups <= 0 && w.cfg.maxAge <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(w.filename)
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(filepath.Base(w.filename), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		t, seq, ok := parseBackupTime(ts)
+		if !ok {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time.Equal(backups[j].time) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].time.After(backups[j].time)
+	})
+
+	for index, backup := range backups {
+		expired := w.cfg.maxAge > 0 && now.Sub(backup.time) > w.cfg.maxAge
+		overflow := w.cfg.maxBackups > 0 && index >= w.cfg.maxBackups
+		if expired || overflow {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !aix && !android && !darwin && !dragonfly && !freebsd && !hurd && !illumos && !ios && !linux && !netbsd && !openbsd && !solaris
+
+package plog
+
+// ReopenOnSignal 没有 SIGHUP 的平台不做任何事情，需要时直接调用 Reopen
+func (w *RotateWriter) ReopenOnSignal() func() {
+	return func() {}
+}
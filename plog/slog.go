@@ -0,0 +1,205 @@
+//go:build go1.21
+
+package plog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SlogLevel plog 的级别转换成 slog 的级别，FATAL 比 slog.LevelError 高一级
+func SlogLevel(levelType LevelType) slog.Level {
+	switch levelType {
+	case FATAL:
+		return slog.LevelError + 4
+	case ERROR:
+		return slog.LevelError
+	case WARN:
+		return slog.LevelWarn
+	case INFO:
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// LevelFromSlog slog 的级别转换成 plog 的级别，比 slog.LevelError 高的也当作 ERROR，不会退出
+func LevelFromSlog(level slog.Level) LevelType {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	}
+	return ERROR
+}
+
+// SlogHandler 用 plog 的 Logger 实现 slog.Handler，attr 转换成字段，group 作为字段的前缀用 . 连接
+type SlogHandler struct {
+	logger *Logger
+	fields []Field
+	prefix string
+}
+
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// Slog 返回写到 logger 的 slog.Logger
+func (logger *Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(logger))
+}
+
+func (c *ChildLogger) Slog() *slog.Logger {
+	return c.logger.Slog()
+}
+
+// Enabled 这里拿不到调用方，只要有包可能打开就返回 true，Handle 里再按 record 的调用方判断
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	levelType := LevelFromSlog(level)
+	if levelType <= h.logger.GetLevel() {
+		return true
+	}
+	lo := h.logger.base().levels.Load()
+	return lo != nil && levelType <= lo.max
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	levelType := LevelFromSlog(record.Level)
+	if !h.logger.enabledFor(levelType, func() string { return slogPackage(record.PC) }) {
+		return nil
+	}
+
+	fields := h.fields
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	entry := &Entry{logger: h.logger, Name: h.logger.name, Context: ctx, Fields: appendFields(h.logger.fields, fields...)}
+	if !record.Time.IsZero() {
+		t := record.Time
+		entry.Time = &t
+	}
+	entry.log(levelType, record.Message, func() *runtime.Frame {
+		return slogFrame(record.PC)
+	})
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := h.fields
+	for _, attr := range attrs {
+		fields = appendAttr(fields, h.prefix, attr)
+	}
+	return &SlogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// appendAttr group 展开成 group.key 的字段，空 key 的 group 直接展开，空 key 的 attr 忽略
+func appendAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, ga := range value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	return appendFields(fields, Field{Key: prefix + attr.Key, Value: value.Any()})
+}
+
+func slogFrame(pc uintptr) *runtime.Frame {
+	if pc == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &frame
+}
+
+func slogPackage(pc uintptr) string {
+	if frame := slogFrame(pc); frame != nil {
+		return getPackageName(frame.Function)
+	}
+	return ""
+}
+
+// SlogWriter 作为 Logger 的 Out，把 entry 转换成 slog.Record 交给 handler
+type SlogWriter struct {
+	handler slog.Handler
+}
+
+func NewSlogWriter(handler slog.Handler) *SlogWriter {
+	return &SlogWriter{handler: handler}
+}
+
+// NewSlogLogger 返回写到 handler 的 Logger，级别由 handler 判断
+func NewSlogLogger(handler slog.Handler) *Logger {
+	logger := NewLogger(DEBUG)
+	logger.Out = NewSlogWriter(handler)
+	return logger
+}
+
+func (w *SlogWriter) WriteEntry(entry *Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := SlogLevel(entry.Level)
+	if !w.handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	var t time.Time
+	if entry.Time != nil {
+		t = *entry.Time
+	}
+	var pc uintptr
+	if entry.CallFrame != nil {
+		// CallersFrames 把 pc 当作返回地址处理，Frame.PC 是调用指令，需要加一
+		pc = entry.CallFrame.PC + 1
+	}
+
+	record := slog.NewRecord(t, level, entry.Msg, pc)
+	if entry.Name != "" {
+		record.AddAttrs(slog.String(DefaultNameKey, entry.Name))
+	}
+	if entry.Err != nil {
+		record.AddAttrs(slog.Any(DefaultErrorKey, entry.Err))
+	}
+	for _, field := range entry.Fields {
+		record.AddAttrs(slog.Any(field.Key, field.Value))
+	}
+	return w.handler.Handle(ctx, record)
+}
+
+// Write 不是通过 Logger 写入的内容当作一条 info 日志
+func (w *SlogWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, slog.LevelInfo) {
+		return len(p), nil
+	}
+
+	msg := string(p)
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+	if err := w.handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
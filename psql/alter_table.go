@@ -0,0 +1,131 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type alterType int
+
+const (
+	addColumn alterType = iota
+	dropColumn
+	modifyColumn
+)
+
+type alterAction struct {
+	alterType alterType
+	column    *ColumnDef
+	name      string
+}
+
+type AlterTableStatement struct {
+	Dialect   Dialect
+	TableName string
+	Actions   []alterAction
+}
+
+func NewAlterTable(dialect Dialect) *AlterTableStatement {
+	return &AlterTableStatement{Dialect: dialect}
+}
+
+func (at *AlterTableStatement) Table(table string) *AlterTableStatement {
+	at.TableName = table
+	return at
+}
+
+func (at *AlterTableStatement) AddColumn(column *ColumnDef) *AlterTableStatement {
+	at.Actions = append(at.Actions, alterAction{alterType: addColumn, column: column})
+	return at
+}
+
+func (at *AlterTableStatement) DropColumn(name string) *AlterTableStatement {
+	at.Actions = append(at.Actions, alterAction{alterType: dropColumn, name: name})
+	return at
+}
+
+func (at *AlterTableStatement) ModifyColumn(column *ColumnDef) *AlterTableStatement {
+	at.Actions = append(at.Actions, alterAction{alterType: modifyColumn, column: column})
+	return at
+}
+
+func (at *AlterTableStatement) ToSql() (query string, args []interface{}, err error) {
+	if at.TableName == "" {
+		return "", nil, fmt.Errorf("alter table sql lack of TableName")
+	}
+	if len(at.Actions) == 0 {
+		return "", nil, fmt.Errorf("alter table sql lack of action")
+	}
+	// sqlite 一条 ALTER TABLE 只能有一个动作
+	if at.Dialect == SQLite && len(at.Actions) > 1 {
+		return "", nil, fmt.Errorf("sqlite alter table only support one action")
+	}
+
+	var actions []string
+	for _, action := range at.Actions {
+		as, err := at.actionToSql(action)
+		if err != nil {
+			return "", nil, err
+		}
+		actions = append(actions, as...)
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s %s", at.TableName, strings.Join(actions, ", ")), nil, nil
+}
+
+func (at *AlterTableStatement) actionToSql(action alterAction) ([]string, error) {
+	switch action.alterType {
+	case addColumn:
+		def, err := action.column.toSql(at.Dialect, false)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("ADD COLUMN %s", def)}, nil
+	case dropColumn:
+		return []string{fmt.Sprintf("DROP COLUMN %s", action.name)}, nil
+	case modifyColumn:
+		switch at.Dialect {
+		case MySQL:
+			def, err := action.column.toSql(at.Dialect, false)
+			if err != nil {
+				return nil, err
+			}
+			return []string{fmt.Sprintf("MODIFY COLUMN %s", def)}, nil
+		case Postgres:
+			return at.postgresModify(action.column)
+		}
+		return nil, fmt.Errorf("%s does not support modify column", at.Dialect)
+	}
+	return nil, fmt.Errorf("unknown alter action %d", action.alterType)
+}
+
+// postgresModify postgres 没有 MODIFY COLUMN，需要把类型、非空、默认值拆开改，
+// 没有显式设置的属性保持不变，避免去掉已有的默认值
+func (at *AlterTableStatement) postgresModify(column *ColumnDef) ([]string, error) {
+	prefix := fmt.Sprintf("ALTER COLUMN %s", column.Name)
+	actions := []string{fmt.Sprintf("%s TYPE %s", prefix, column.Type.name(at.Dialect))}
+
+	if column.NotNullValue {
+		actions = append(actions, fmt.Sprintf("%s SET NOT NULL", prefix))
+	} else if column.nullable {
+		actions = append(actions, fmt.Sprintf("%s DROP NOT NULL", prefix))
+	}
+
+	if column.hasDefault {
+		literal, err := at.Dialect.literal(column.DefaultValue)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, fmt.Sprintf("%s SET DEFAULT %s", prefix, literal))
+	} else if column.dropDefault {
+		actions = append(actions, fmt.Sprintf("%s DROP DEFAULT", prefix))
+	}
+
+	return actions, nil
+}
+
+func (at *AlterTableStatement) Clone() *AlterTableStatement {
+	clone := *at
+	clone.Actions = append([]alterAction(nil), at.Actions...)
+	return &clone
+}
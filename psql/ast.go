@@ -0,0 +1,499 @@
+package psql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ASTVersion json 结构变化时加一，旧版本的数据需要迁移后才能读取
+const ASTVersion = 1
+
+const (
+	opAnd = "and"
+	opOr  = "or"
+)
+
+var ErrFilterUnknownField = errors.New("filter field unknown")
+
+type CondNode struct {
+	Op string `json:"op"`
+	// 比较操作时是 schema 字段名到值的映射
+	Values map[string]interface{} `json:"values,omitempty"`
+	// and、or 时的子条件
+	Conds []*CondNode `json:"conds,omitempty"`
+	// like 的模式用 ! 转义了 % 和 _，比如 FilterSchema 解析出来的条件
+	Escape bool `json:"escape,omitempty"`
+}
+
+type OrderNode struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+	Nulls string `json:"nulls,omitempty"`
+}
+
+type SelectNode struct {
+	Table    string       `json:"table"`
+	Distinct bool         `json:"distinct,omitempty"`
+	Columns  []string     `json:"columns"`
+	Where    *CondNode    `json:"where,omitempty"`
+	GroupBy  []string     `json:"group_by,omitempty"`
+	OrderBy  []*OrderNode `json:"order_by,omitempty"`
+	Limit    *int64       `json:"limit,omitempty"`
+	Offset   *int64       `json:"offset,omitempty"`
+}
+
+type condDocument struct {
+	Version int       `json:"version"`
+	Cond    *CondNode `json:"cond"`
+}
+
+type selectDocument struct {
+	Version int         `json:"version"`
+	Select  *SelectNode `json:"select"`
+}
+
+// MarshalCond 条件里的列会按 schema 换成字段名，和 UnmarshalCond 对称，不在 schema 里的列返回错误
+func MarshalCond(cond SqlCond, schema FilterSchema) ([]byte, error) {
+	node, err := condToNode(cond, schema.fieldKeys())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(condDocument{Version: ASTVersion, Cond: node})
+}
+
+// UnmarshalCond values 里的 key 必须是 schema 里的字段，并且操作符是允许的，值会按字段类型转换
+func UnmarshalCond(data []byte, schema FilterSchema) (SqlCond, error) {
+	var doc condDocument
+	if err := decodeAST(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != ASTVersion {
+		return nil, fmt.Errorf("condition ast version not supported, version = %d", doc.Version)
+	}
+	if doc.Cond == nil {
+		return nil, fmt.Errorf("condition ast lack of cond")
+	}
+	return nodeToCond(doc.Cond, schema)
+}
+
+// MarshalSelect 表名必须是 schemas 的 key，列、条件、排序按这个表的 schema 换成字段名
+func MarshalSelect(st *SelectStatement, schemas map[string]FilterSchema) ([]byte, error) {
+	if len(st.Joins) > 0 || len(st.Windows) > 0 {
+		return nil, fmt.Errorf("select ast not support join and window")
+	}
+
+	schema, ok := schemas[st.TableName]
+	if !ok {
+		return nil, &FilterError{Key: st.TableName, Err: ErrFilterUnknownField}
+	}
+	keys := schema.fieldKeys()
+
+	node := &SelectNode{
+		Table:    st.TableName,
+		Distinct: st.DistinctValue,
+		Limit:    st.LimitValue,
+		Offset:   st.OffsetValue,
+	}
+
+	columns, err := plainColumns(st.Columns)
+	if err != nil {
+		return nil, err
+	}
+	if node.Columns, err = columnFields(keys, columns); err != nil {
+		return nil, err
+	}
+	groupBys, err := plainColumns(st.GroupBys)
+	if err != nil {
+		return nil, err
+	}
+	if node.GroupBy, err = columnFields(keys, groupBys); err != nil {
+		return nil, err
+	}
+
+	switch len(st.Wheres) {
+	case 0:
+	case 1:
+		node.Where, err = condToNode(st.Wheres[0], keys)
+	default:
+		node.Where, err = condToNode(And(st.Wheres), keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, orderBy := range st.OrderBys {
+		term, ok := orderBy.(OrderTerm)
+		if !ok {
+			return nil, fmt.Errorf("select ast only support OrderTerm, order by = %#v", orderBy)
+		}
+		fields, err := columnFields(keys, []string{term.Column})
+		if err != nil {
+			return nil, err
+		}
+		on := &OrderNode{Field: fields[0], Desc: term.Direction == DescOrder}
+		switch term.Nulls {
+		case NullsFirstOrder:
+			on.Nulls = "first"
+		case NullsLastOrder:
+			on.Nulls = "last"
+		}
+		node.OrderBy = append(node.OrderBy, on)
+	}
+
+	return json.Marshal(selectDocument{Version: ASTVersion, Select: node})
+}
+
+// UnmarshalSelect 表名必须是 schemas 的 key，列、条件、排序都要在这个表的 schema 里
+func UnmarshalSelect(data []byte, schemas map[string]FilterSchema) (*SelectStatement, error) {
+	return NewSqlBuilder(Question).UnmarshalSelect(data, schemas)
+}
+
+// UnmarshalSelect 通过 builder 生成语句，作用域、软删除、分片这些设置和 builder.Select 一样生效
+func (s SqlBuilder) UnmarshalSelect(data []byte, schemas map[string]FilterSchema) (*SelectStatement, error) {
+	var doc selectDocument
+	if err := decodeAST(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != ASTVersion {
+		return nil, fmt.Errorf("select ast version not supported, version = %d", doc.Version)
+	}
+	node := doc.Select
+	if node == nil {
+		return nil, fmt.Errorf("select ast lack of select")
+	}
+
+	schema, ok := schemas[node.Table]
+	if !ok {
+		return nil, &FilterError{Key: node.Table, Err: ErrFilterUnknownField}
+	}
+
+	st := s.Select().From(node.Table)
+	st.DistinctValue = node.Distinct
+	columns, err := schemaColumns(schema, node.Columns)
+	if err != nil {
+		return nil, err
+	}
+	st.Column(columns...)
+
+	groupBys, err := schemaColumns(schema, node.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	st.GroupBy(groupBys...)
+
+	if node.Where != nil {
+		cond, err := nodeToCond(node.Where, schema)
+		if err != nil {
+			return nil, err
+		}
+		st.Where(cond)
+	}
+
+	for _, on := range node.OrderBy {
+		columns, err := schemaColumns(schema, []string{on.Field})
+		if err != nil {
+			return nil, err
+		}
+		term := Asc(columns[0])
+		if on.Desc {
+			term = Desc(columns[0])
+		}
+		switch on.Nulls {
+		case "":
+		case "first":
+			term = term.NullsFirst()
+		case "last":
+			term = term.NullsLast()
+		default:
+			return nil, &FilterError{Key: on.Field, Value: on.Nulls, Err: ErrFilterInvalidValue}
+		}
+		st.OrderByTerms(term)
+	}
+
+	if node.Limit != nil {
+		st.Limit(*node.Limit)
+	}
+	if node.Offset != nil {
+		st.Offset(*node.Offset)
+	}
+	return st, nil
+}
+
+func decodeAST(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func plainColumns(conds []SqlCond) ([]string, error) {
+	var columns []string
+	for _, cond := range conds {
+		param, ok := cond.(SqlParam)
+		if !ok {
+			return nil, fmt.Errorf("ast only support plain column, column = %#v", cond)
+		}
+		column, ok := param.query.(string)
+		if !ok || len(param.args) > 0 {
+			return nil, fmt.Errorf("ast only support plain column, column = %#v", cond)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// fieldKeys 列名到字段名的映射，多个字段对应同一列时取排序后的第一个
+func (fs FilterSchema) fieldKeys() map[string]string {
+	keys := make(map[string]string, len(fs))
+	for key, field := range fs {
+		if exist, ok := keys[field.Column]; !ok || key < exist {
+			keys[field.Column] = key
+		}
+	}
+	return keys
+}
+
+func columnFields(keys map[string]string, columns []string) ([]string, error) {
+	var fields []string
+	for _, column := range columns {
+		field, ok := keys[column]
+		if !ok {
+			return nil, &FilterError{Key: column, Err: ErrFilterUnknownField}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func schemaColumns(schema FilterSchema, fields []string) ([]string, error) {
+	var columns []string
+	for _, field := range fields {
+		ff, ok := schema[field]
+		if !ok {
+			return nil, &FilterError{Key: field, Err: ErrFilterUnknownField}
+		}
+		columns = append(columns, ff.Column)
+	}
+	return columns, nil
+}
+
+// condToNode keys 是列名到字段名的映射
+func condToNode(cond SqlCond, keys map[string]string) (*CondNode, error) {
+	switch c := cond.(type) {
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			return condToNode(q, keys)
+		case map[string]interface{}:
+			return condToNode(Eq(q), keys)
+		}
+		return nil, fmt.Errorf("ast not support raw condition, condition = %#v", c.query)
+	case And:
+		return groupToNode(opAnd, c, keys)
+	case Or:
+		return groupToNode(opOr, c, keys)
+	case likeCond:
+		op := OpLike
+		if c.not {
+			op = OpNotLike
+		}
+		field, ok := keys[c.column]
+		if !ok {
+			return nil, &FilterError{Key: c.column, Op: op, Err: ErrFilterUnknownField}
+		}
+		return &CondNode{Op: string(op), Values: map[string]interface{}{field: c.pattern}, Escape: true}, nil
+	}
+
+	var op FilterOp
+	switch cond.(type) {
+	case Eq:
+		op = OpEq
+	case NotEq:
+		op = OpNotEq
+	case Like:
+		op = OpLike
+	case NotLike:
+		op = OpNotLike
+	case Lt:
+		op = OpLt
+	case Lte:
+		op = OpLte
+	case Gt:
+		op = OpGt
+	case Gte:
+		op = OpGte
+	default:
+		return nil, fmt.Errorf("ast not support condition, condition = %#v", cond)
+	}
+
+	data, _ := condExpr(cond)
+	values := make(map[string]interface{}, len(data))
+	for column, value := range data {
+		field, ok := keys[column]
+		if !ok {
+			return nil, &FilterError{Key: column, Op: op, Err: ErrFilterUnknownField}
+		}
+		values[field] = value
+	}
+	return &CondNode{Op: string(op), Values: values}, nil
+}
+
+func groupToNode(op string, conds []SqlCond, keys map[string]string) (*CondNode, error) {
+	node := &CondNode{Op: op}
+	for _, cond := range conds {
+		child, err := condToNode(cond, keys)
+		if err != nil {
+			return nil, err
+		}
+		node.Conds = append(node.Conds, child)
+	}
+	return node, nil
+}
+
+func nodeToCond(node *CondNode, schema FilterSchema) (SqlCond, error) {
+	switch node.Op {
+	case opAnd, opOr:
+		if len(node.Conds) == 0 {
+			return nil, fmt.Errorf("condition ast %s lack of conds", node.Op)
+		}
+		var conds []SqlCond
+		for _, child := range node.Conds {
+			cond, err := nodeToCond(child, schema)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, cond)
+		}
+		if node.Op == opAnd {
+			return And(conds), nil
+		}
+		return Or(conds), nil
+	}
+
+	if len(node.Values) == 0 {
+		return nil, fmt.Errorf("condition ast %s lack of values", node.Op)
+	}
+
+	op := FilterOp(node.Op)
+	if node.Escape && op != OpLike && op != OpNotLike {
+		return nil, fmt.Errorf("condition ast escape only support like, op = %s", op)
+	}
+	data := make(expr, len(node.Values))
+	var fields []string
+	for field := range node.Values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		ff, ok := schema[field]
+		if !ok {
+			return nil, &FilterError{Key: field, Op: op, Err: ErrFilterUnknownField}
+		}
+		if !ff.allow(op) {
+			return nil, &FilterError{Key: field, Op: op, Err: ErrFilterOpNotAllowed}
+		}
+		value, err := ff.convertJSON(op, node.Values[field])
+		if err != nil {
+			return nil, &FilterError{Key: field, Op: op, Value: fmt.Sprint(node.Values[field]), Err: fmt.Errorf("%w: %v", ErrFilterInvalidValue, err)}
+		}
+		data[ff.Column] = value
+	}
+
+	if node.Escape {
+		var conds And
+		for _, column := range sortedKeys(data) {
+			conds = append(conds, likeCond{column: column, pattern: data[column].(string), not: op == OpNotLike})
+		}
+		if len(conds) == 1 {
+			return conds[0], nil
+		}
+		return conds, nil
+	}
+
+	switch op {
+	case OpEq:
+		return Eq(data), nil
+	case OpNotEq:
+		return NotEq(data), nil
+	case OpLike:
+		return Like(data), nil
+	case OpNotLike:
+		return NotLike(data), nil
+	case OpLt:
+		return Lt(data), nil
+	case OpLte:
+		return Lte(data), nil
+	case OpGt:
+		return Gt(data), nil
+	case OpGte:
+		return Gte(data), nil
+	}
+	return nil, &FilterError{Op: op, Err: ErrFilterOpNotAllowed}
+}
+
+// convertJSON json 解出来的值按字段类型转换，eq、ne 允许 null 和列表
+func (ff FilterField) convertJSON(op FilterOp, value interface{}) (interface{}, error) {
+	if list, ok := value.([]interface{}); ok {
+		if op != OpEq && op != OpNotEq {
+			return nil, fmt.Errorf("list only support eq and ne")
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("list is empty")
+		}
+		var values []interface{}
+		for _, item := range list {
+			v, err := ff.convertJSONValue(op, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	if value == nil {
+		if op != OpEq && op != OpNotEq {
+			return nil, fmt.Errorf("null only support eq and ne")
+		}
+		return nil, nil
+	}
+	return ff.convertJSONValue(op, value)
+}
+
+func (ff FilterField) convertJSONValue(op FilterOp, value interface{}) (interface{}, error) {
+	if op == OpLike || op == OpNotLike {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("like value must be string")
+		}
+		return s, nil
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		switch ff.Type {
+		case IntField:
+			return v.Int64()
+		case FloatField:
+			return v.Float64()
+		}
+	case string:
+		switch ff.Type {
+		case StringField:
+			return v, nil
+		case TimeField:
+			return time.Parse(time.RFC3339, v)
+		}
+	case bool:
+		if ff.Type == BoolField {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("value type %s not match field type", strings.ToLower(reflect.TypeOf(value).Kind().String()))
+}
@@ -0,0 +1,129 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type whenParam struct {
+	when interface{}
+	then interface{}
+	args []interface{}
+}
+
+type CaseExpr struct {
+	Value     interface{}
+	Whens     []whenParam
+	ElseValue interface{}
+	hasElse   bool
+}
+
+// Case 不传参数时为 CASE WHEN cond THEN ... 形式，传入参数时为 CASE value WHEN ... 形式
+func Case(value ...interface{}) *CaseExpr {
+	ce := &CaseExpr{}
+	if len(value) > 0 {
+		ce.Value = value[0]
+	}
+	return ce
+}
+
+// When args 是 when 为字符串条件时占位符的参数，比如 When("price > ?", "high", 100)
+func (ce *CaseExpr) When(when interface{}, then interface{}, args ...interface{}) *CaseExpr {
+	ce.Whens = append(ce.Whens, whenParam{when: when, then: then, args: args})
+	return ce
+}
+
+func (ce *CaseExpr) Else(value interface{}) *CaseExpr {
+	ce.ElseValue = value
+	ce.hasElse = true
+	return ce
+}
+
+func (ce *CaseExpr) clone() *CaseExpr {
+	if ce == nil {
+		return nil
+	}
+	clone := *ce
+	clone.Value = cloneValue(ce.Value)
+	clone.ElseValue = cloneValue(ce.ElseValue)
+	clone.Whens = make([]whenParam, 0, len(ce.Whens))
+	for _, wp := range ce.Whens {
+		clone.Whens = append(clone.Whens, whenParam{when: cloneValue(wp.when), then: cloneValue(wp.then), args: wp.args})
+	}
+	return &clone
+}
+
+func (ce *CaseExpr) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if len(ce.Whens) == 0 {
+		return "", nil, fmt.Errorf("case expression lack of when")
+	}
+
+	var sql strings.Builder
+	sql.WriteString("CASE")
+
+	if ce.Value != nil {
+		vq, vs, err := operandToSql(ce.Value, pt)
+		if err != nil {
+			return "", nil, err
+		}
+		sql.WriteString(fmt.Sprintf(" %s", vq))
+		args = append(args, vs...)
+	}
+
+	for _, wp := range ce.Whens {
+		var wq string
+		var ws []interface{}
+		if ce.Value != nil {
+			if len(wp.args) > 0 {
+				return "", nil, fmt.Errorf("case value form when not support args")
+			}
+			// CASE value WHEN ? 的形式，when 是值
+			wq, ws, err = valueToSql(wp.when, pt)
+		} else {
+			wq, ws, err = SqlParam{query: wp.when, args: wp.args}.ToWhere(pt)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, ws...)
+
+		tq, ts, err := valueToSql(wp.then, pt)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, ts...)
+		sql.WriteString(fmt.Sprintf(" WHEN %s THEN %s", wq, tq))
+	}
+
+	if ce.hasElse {
+		eq, es, err := valueToSql(ce.ElseValue, pt)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, es...)
+		sql.WriteString(fmt.Sprintf(" ELSE %s", eq))
+	}
+	sql.WriteString(" END")
+
+	return sql.String(), args, nil
+}
+
+func (ce *CaseExpr) ToSql() (query string, args []interface{}, err error) {
+	return ce.ToWhere(Question)
+}
+
+// operandToSql 字符串当作列名原样输出，SqlCond 展开，其余当作值
+func operandToSql(value interface{}, pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if column, ok := value.(string); ok {
+		return column, nil, nil
+	}
+	return valueToSql(value, pt)
+}
+
+// valueToSql SqlCond 展开，其余使用占位符
+func valueToSql(value interface{}, pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if sc, ok := value.(SqlCond); ok {
+		return sc.ToWhere(pt)
+	}
+	return pt.Mark(), []interface{}{value}, nil
+}
@@ -0,0 +1,73 @@
+package psql
+
+import "fmt"
+
+type SqlExpr struct {
+	query string
+	args  []interface{}
+}
+
+// Expr 原样输出 query，args 按出现的位置带出，比如 Expr("COALESCE(name, ?)", "none")
+func Expr(query string, args ...interface{}) SqlExpr {
+	return SqlExpr{query: query, args: args}
+}
+
+func (e SqlExpr) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	return e.query, e.args, nil
+}
+
+func (e SqlExpr) As(alias string) AliasExpr {
+	return As(e, alias)
+}
+
+type AliasExpr struct {
+	Expr  SqlCond
+	Alias string
+}
+
+func As(expr SqlCond, alias string) AliasExpr {
+	return AliasExpr{Expr: expr, Alias: alias}
+}
+
+func (ae AliasExpr) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if ae.Alias == "" {
+		return "", nil, fmt.Errorf("alias expression lack of alias")
+	}
+	query, args, err = ae.Expr.ToWhere(pt)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s AS %s", query, ae.Alias), args, nil
+}
+
+func (ce *CaseExpr) As(alias string) AliasExpr {
+	return As(ce, alias)
+}
+
+func aggregate(fn, column string) SqlExpr {
+	return Expr(fmt.Sprintf("%s(%s)", fn, column))
+}
+
+func Count(column string) SqlExpr {
+	return aggregate("COUNT", column)
+}
+
+func CountDistinct(column string) SqlExpr {
+	return aggregate("COUNT", "DISTINCT "+column)
+}
+
+func Sum(column string) SqlExpr {
+	return aggregate("SUM", column)
+}
+
+func Max(column string) SqlExpr {
+	return aggregate("MAX", column)
+}
+
+func Min(column string) SqlExpr {
+	return aggregate("MIN", column)
+}
+
+func Avg(column string) SqlExpr {
+	return aggregate("AVG", column)
+}
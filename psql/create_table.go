@@ -0,0 +1,139 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type uniqueDef struct {
+	name    string
+	columns []string
+}
+
+type CreateTableStatement struct {
+	Dialect     Dialect
+	TableName   string
+	IfNotExist  bool
+	Columns     []*ColumnDef
+	PrimaryKeys []string
+	Uniques     []uniqueDef
+	ForeignKeys []*ForeignKeyDef
+}
+
+func NewCreateTable(dialect Dialect) *CreateTableStatement {
+	return &CreateTableStatement{Dialect: dialect}
+}
+
+func (ct *CreateTableStatement) Table(table string) *CreateTableStatement {
+	ct.TableName = table
+	return ct
+}
+
+func (ct *CreateTableStatement) IfNotExists() *CreateTableStatement {
+	ct.IfNotExist = true
+	return ct
+}
+
+func (ct *CreateTableStatement) Column(columns ...*ColumnDef) *CreateTableStatement {
+	ct.Columns = append(ct.Columns, columns...)
+	return ct
+}
+
+func (ct *CreateTableStatement) PrimaryKey(columns ...string) *CreateTableStatement {
+	ct.PrimaryKeys = append(ct.PrimaryKeys, columns...)
+	return ct
+}
+
+func (ct *CreateTableStatement) Unique(name string, columns ...string) *CreateTableStatement {
+	ct.Uniques = append(ct.Uniques, uniqueDef{name: name, columns: columns})
+	return ct
+}
+
+func (ct *CreateTableStatement) ForeignKey(foreignKeys ...*ForeignKeyDef) *CreateTableStatement {
+	ct.ForeignKeys = append(ct.ForeignKeys, foreignKeys...)
+	return ct
+}
+
+func (ct *CreateTableStatement) ToSql() (query string, args []interface{}, err error) {
+	if ct.TableName == "" {
+		return "", nil, fmt.Errorf("create table sql lack of TableName")
+	}
+	if len(ct.Columns) == 0 {
+		return "", nil, fmt.Errorf("create table sql lack of column")
+	}
+
+	var sql strings.Builder
+	sql.WriteString("CREATE TABLE ")
+	if ct.IfNotExist {
+		sql.WriteString("IF NOT EXISTS ")
+	}
+	sql.WriteString(ct.TableName)
+	sql.WriteString(" (")
+
+	inline := ct.inlinePrimary()
+	var defs []string
+	for _, column := range ct.Columns {
+		def, err := column.toSql(ct.Dialect, column.Name == inline)
+		if err != nil {
+			return "", nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	if len(ct.PrimaryKeys) > 0 && inline == "" {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(ct.PrimaryKeys, ",")))
+	}
+	for _, unique := range ct.Uniques {
+		if len(unique.columns) == 0 {
+			return "", nil, fmt.Errorf("unique constraint lack of column, name = %s", unique.name)
+		}
+		// 没有名字时由数据库自己命名
+		def := fmt.Sprintf("UNIQUE (%s)", strings.Join(unique.columns, ","))
+		if unique.name != "" {
+			def = fmt.Sprintf("CONSTRAINT %s %s", unique.name, def)
+		}
+		defs = append(defs, def)
+	}
+	for _, fk := range ct.ForeignKeys {
+		def, err := fk.toSql()
+		if err != nil {
+			return "", nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	sql.WriteString(strings.Join(defs, ", "))
+	sql.WriteString(")")
+
+	return sql.String(), nil, nil
+}
+
+// inlinePrimary sqlite 的自增列只能写成 INTEGER PRIMARY KEY AUTOINCREMENT，这时不再单独写主键
+func (ct *CreateTableStatement) inlinePrimary() string {
+	if ct.Dialect != SQLite || len(ct.PrimaryKeys) != 1 {
+		return ""
+	}
+	for _, column := range ct.Columns {
+		if column.Name == ct.PrimaryKeys[0] && column.AutoIncrValue {
+			return column.Name
+		}
+	}
+	return ""
+}
+
+func (ct *CreateTableStatement) Clone() *CreateTableStatement {
+	clone := *ct
+	clone.Columns = nil
+	for _, column := range ct.Columns {
+		c := *column
+		clone.Columns = append(clone.Columns, &c)
+	}
+	clone.PrimaryKeys = append([]string(nil), ct.PrimaryKeys...)
+	clone.Uniques = append([]uniqueDef(nil), ct.Uniques...)
+	clone.ForeignKeys = nil
+	for _, fk := range ct.ForeignKeys {
+		f := *fk
+		clone.ForeignKeys = append(clone.ForeignKeys, &f)
+	}
+	return &clone
+}
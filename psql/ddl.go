@@ -0,0 +1,145 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ColumnDef struct {
+	Name          string
+	Type          ColumnType
+	NotNullValue  bool
+	DefaultValue  interface{}
+	hasDefault    bool
+	AutoIncrValue bool
+	// postgres 修改列时只改显式设置过的属性
+	nullable    bool
+	dropDefault bool
+}
+
+func NewColumnDef(name string, columnType ColumnType) *ColumnDef {
+	return &ColumnDef{Name: name, Type: columnType}
+}
+
+func (cd *ColumnDef) NotNull() *ColumnDef {
+	cd.NotNullValue = true
+	cd.nullable = false
+	return cd
+}
+
+// Nullable 修改列时去掉非空约束
+func (cd *ColumnDef) Nullable() *ColumnDef {
+	cd.NotNullValue = false
+	cd.nullable = true
+	return cd
+}
+
+// Default 字符串会被当作字面量加引号，需要表达式时使用 Expr，比如 Default(Expr("CURRENT_TIMESTAMP"))
+func (cd *ColumnDef) Default(value interface{}) *ColumnDef {
+	cd.DefaultValue = value
+	cd.hasDefault = true
+	cd.dropDefault = false
+	return cd
+}
+
+// DropDefault 修改列时去掉默认值
+func (cd *ColumnDef) DropDefault() *ColumnDef {
+	cd.DefaultValue = nil
+	cd.hasDefault = false
+	cd.dropDefault = true
+	return cd
+}
+
+func (cd *ColumnDef) AutoIncrement() *ColumnDef {
+	cd.AutoIncrValue = true
+	cd.NotNullValue = true
+	return cd
+}
+
+func (cd *ColumnDef) toSql(d Dialect, inlinePrimary bool) (string, error) {
+	var sql strings.Builder
+	sql.WriteString(fmt.Sprintf("%s %s", cd.Name, cd.Type.name(d)))
+
+	if cd.AutoIncrValue {
+		switch d {
+		case MySQL:
+			sql.WriteString(" NOT NULL AUTO_INCREMENT")
+		case Postgres:
+			sql.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+		case SQLite:
+			if !inlinePrimary {
+				return "", fmt.Errorf("sqlite auto increment column %s must be the only primary key", cd.Name)
+			}
+			sql.WriteString(" PRIMARY KEY AUTOINCREMENT")
+		}
+		return sql.String(), nil
+	}
+
+	if cd.NotNullValue {
+		sql.WriteString(" NOT NULL")
+	}
+	if cd.hasDefault {
+		literal, err := d.literal(cd.DefaultValue)
+		if err != nil {
+			return "", err
+		}
+		sql.WriteString(fmt.Sprintf(" DEFAULT %s", literal))
+	}
+
+	return sql.String(), nil
+}
+
+type ForeignKeyDef struct {
+	Columns        []string
+	RefTable       string
+	RefColumns     []string
+	OnDeleteAction string
+	OnUpdateAction string
+	ConstraintName string
+}
+
+func ForeignKey(columns ...string) *ForeignKeyDef {
+	return &ForeignKeyDef{Columns: columns}
+}
+
+func (fk *ForeignKeyDef) Name(name string) *ForeignKeyDef {
+	fk.ConstraintName = name
+	return fk
+}
+
+func (fk *ForeignKeyDef) References(table string, columns ...string) *ForeignKeyDef {
+	fk.RefTable = table
+	fk.RefColumns = columns
+	return fk
+}
+
+// OnDelete action 比如 CASCADE、SET NULL、RESTRICT
+func (fk *ForeignKeyDef) OnDelete(action string) *ForeignKeyDef {
+	fk.OnDeleteAction = action
+	return fk
+}
+
+func (fk *ForeignKeyDef) OnUpdate(action string) *ForeignKeyDef {
+	fk.OnUpdateAction = action
+	return fk
+}
+
+func (fk *ForeignKeyDef) toSql() (string, error) {
+	if len(fk.Columns) == 0 || fk.RefTable == "" || len(fk.RefColumns) == 0 {
+		return "", fmt.Errorf("foreign key lack of column or reference. foreign key = %#v", fk)
+	}
+
+	var sql strings.Builder
+	if fk.ConstraintName != "" {
+		sql.WriteString(fmt.Sprintf("CONSTRAINT %s ", fk.ConstraintName))
+	}
+	sql.WriteString(fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		strings.Join(fk.Columns, ","), fk.RefTable, strings.Join(fk.RefColumns, ",")))
+	if fk.OnDeleteAction != "" {
+		sql.WriteString(fmt.Sprintf(" ON DELETE %s", fk.OnDeleteAction))
+	}
+	if fk.OnUpdateAction != "" {
+		sql.WriteString(fmt.Sprintf(" ON UPDATE %s", fk.OnUpdateAction))
+	}
+	return sql.String(), nil
+}
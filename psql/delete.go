@@ -3,12 +3,18 @@ package psql
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type DeleteStatement struct {
-	HolderType PlaceHolderType
-	TableName  string
-	Wheres     []SqlCond
+	HolderType       PlaceHolderType
+	TableName        string
+	Wheres           []SqlCond
+	SoftDeleteColumn string
+	Scope            Eq
+	Shards           map[string]ShardStrategy
+	// 传入过空的条件，没有其他条件时 ToSql 报错，不会变成删除整张表
+	emptyWhere bool
 }
 
 func NewDelete(holderType PlaceHolderType) *DeleteStatement {
@@ -20,28 +26,76 @@ func (t *DeleteStatement) Table(table string) *DeleteStatement {
 	return t
 }
 
+// Where 空的条件会被忽略，只有空的条件时 ToSql 返回错误
 func (t *DeleteStatement) Where(query interface{}, args ...interface{}) *DeleteStatement {
+	if isEmptyCond(query) {
+		t.emptyWhere = true
+		return t
+	}
 	t.Wheres = append(t.Wheres, SqlParam{query: query, args: args})
 	return t
 }
 
+func (t *DeleteStatement) Unscoped() *DeleteStatement {
+	t.Scope = nil
+	return t
+}
+
+// Hard 软删除模式下也直接删除数据
+func (t *DeleteStatement) Hard() *DeleteStatement {
+	t.SoftDeleteColumn = ""
+	return t
+}
+
 func (t *DeleteStatement) ToSql() (query string, args []interface{}, err error) {
+	if t.emptyWhere && len(t.Wheres) == 0 {
+		return "", nil, fmt.Errorf("delete sql where condition is empty")
+	}
+	if t.SoftDeleteColumn != "" {
+		return t.softUpdate().ToSql()
+	}
+
+	wheres, err := scopeWheres(t.Scope, t.Wheres)
+	if err != nil {
+		return
+	}
+	table, err := shardTable(t.Shards, t.TableName, wheres)
+	if err != nil {
+		return
+	}
+
 	var sql strings.Builder
-	_, err = sql.WriteString(fmt.Sprintf("DELETE FROM %s ", t.TableName))
+	_, err = sql.WriteString(fmt.Sprintf("DELETE FROM %s ", table))
 	if err != nil {
 		return
 	}
-	if len(t.Wheres) > 0 {
+	if len(wheres) > 0 {
 		_, err = sql.WriteString("WHERE ")
 		if err != nil {
 			return
 		}
 
-		args, err = appendToSql(t.Wheres, " AND ", &sql, args, t.HolderType)
+		args, err = appendToSql(wheres, " AND ", &sql, args, t.HolderType)
 		if err != nil {
 			return
 		}
 	}
 
-	return sql.String(), args, nil
+	return t.HolderType.replace(sql.String()), args, nil
+}
+
+// softUpdate 软删除转成更新删除时间的语句
+func (t *DeleteStatement) softUpdate() *UpdateStatement {
+	update := NewUpdate(t.HolderType).Table(t.TableName).Set(t.SoftDeleteColumn, time.Now())
+	// 已经删除的行不再更新删除时间
+	update.Wheres = append(groupConds(t.Wheres), Eq{t.SoftDeleteColumn: nil})
+	update.Scope = t.Scope
+	update.Shards = t.Shards
+	return update
+}
+
+func (t *DeleteStatement) Clone() *DeleteStatement {
+	clone := *t
+	clone.Wheres = cloneConds(t.Wheres)
+	return &clone
 }
@@ -0,0 +1,153 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
+	SQLite
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case Postgres:
+		return "postgres"
+	case SQLite:
+		return "sqlite"
+	}
+	return ""
+}
+
+// PlaceHolder postgres 使用 $n，其他使用 ?
+func (d Dialect) PlaceHolder() PlaceHolderType {
+	if d == Postgres {
+		return Dollar
+	}
+	return Question
+}
+
+type typeKind int
+
+const (
+	intKind typeKind = iota
+	bigIntKind
+	varcharKind
+	textKind
+	boolKind
+	timestampKind
+	decimalKind
+	doubleKind
+	jsonKind
+)
+
+type ColumnType struct {
+	kind  typeKind
+	size  int
+	scale int
+}
+
+var (
+	IntType       = ColumnType{kind: intKind}
+	BigIntType    = ColumnType{kind: bigIntKind}
+	TextType      = ColumnType{kind: textKind}
+	BoolType      = ColumnType{kind: boolKind}
+	TimestampType = ColumnType{kind: timestampKind}
+	DoubleType    = ColumnType{kind: doubleKind}
+	JSONType      = ColumnType{kind: jsonKind}
+)
+
+func VarcharType(size int) ColumnType {
+	return ColumnType{kind: varcharKind, size: size}
+}
+
+func DecimalType(precision, scale int) ColumnType {
+	return ColumnType{kind: decimalKind, size: precision, scale: scale}
+}
+
+func (ct ColumnType) name(d Dialect) string {
+	switch ct.kind {
+	case intKind:
+		if d == MySQL {
+			return "INT"
+		}
+		return "INTEGER"
+	case bigIntKind:
+		// sqlite 只有 INTEGER PRIMARY KEY 才能自增
+		if d == SQLite {
+			return "INTEGER"
+		}
+		return "BIGINT"
+	case varcharKind:
+		return fmt.Sprintf("VARCHAR(%d)", ct.size)
+	case textKind:
+		return "TEXT"
+	case boolKind:
+		switch d {
+		case MySQL:
+			return "TINYINT(1)"
+		case SQLite:
+			return "INTEGER"
+		}
+		return "BOOLEAN"
+	case timestampKind:
+		if d == Postgres {
+			return "TIMESTAMP"
+		}
+		return "DATETIME"
+	case decimalKind:
+		if d == MySQL {
+			return fmt.Sprintf("DECIMAL(%d,%d)", ct.size, ct.scale)
+		}
+		return fmt.Sprintf("NUMERIC(%d,%d)", ct.size, ct.scale)
+	case doubleKind:
+		switch d {
+		case Postgres:
+			return "DOUBLE PRECISION"
+		case SQLite:
+			return "REAL"
+		}
+		return "DOUBLE"
+	case jsonKind:
+		switch d {
+		case Postgres:
+			return "JSONB"
+		case SQLite:
+			return "TEXT"
+		}
+		return "JSON"
+	}
+	return ""
+}
+
+// literal DDL 不能用占位符，默认值需要直接写成字面量
+func (d Dialect) literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''")), nil
+	case bool:
+		if d == Postgres {
+			if v {
+				return "TRUE", nil
+			}
+			return "FALSE", nil
+		}
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v), nil
+	case SqlExpr:
+		return v.query, nil
+	}
+	return "", fmt.Errorf("default value has wrong type. value = %#v", value)
+}
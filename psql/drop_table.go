@@ -0,0 +1,37 @@
+package psql
+
+import "fmt"
+
+type DropTableStatement struct {
+	TableName string
+	IfExist   bool
+}
+
+func NewDropTable() *DropTableStatement {
+	return &DropTableStatement{}
+}
+
+func (dt *DropTableStatement) Table(table string) *DropTableStatement {
+	dt.TableName = table
+	return dt
+}
+
+func (dt *DropTableStatement) IfExists() *DropTableStatement {
+	dt.IfExist = true
+	return dt
+}
+
+func (dt *DropTableStatement) ToSql() (query string, args []interface{}, err error) {
+	if dt.TableName == "" {
+		return "", nil, fmt.Errorf("drop table sql lack of TableName")
+	}
+	if dt.IfExist {
+		return fmt.Sprintf("DROP TABLE IF EXISTS %s", dt.TableName), nil, nil
+	}
+	return fmt.Sprintf("DROP TABLE %s", dt.TableName), nil, nil
+}
+
+func (dt *DropTableStatement) Clone() *DropTableStatement {
+	clone := *dt
+	return &clone
+}
@@ -0,0 +1,54 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Executor *sql.DB、*sql.Conn、*sql.Tx 都满足
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// ConflictError 乐观锁更新没有影响任何行，说明数据已经被别人改过了
+type ConflictError struct {
+	Table   string
+	Version interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("psql: optimistic lock conflict, table = %s, version = %v", e.Table, e.Version)
+}
+
+func Exec(ctx context.Context, executor Executor, st SqlStatement) (sql.Result, error) {
+	query, args, err := st.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	result, err := executor.ExecContext(withStatement(ctx, st), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ut, ok := st.(*UpdateStatement); ok && ut.VersionColumn != "" {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if affected == 0 {
+			return result, &ConflictError{Table: ut.TableName, Version: ut.VersionValue}
+		}
+	}
+	return result, nil
+}
+
+func Query(ctx context.Context, executor Executor, st SqlStatement) (*sql.Rows, error) {
+	query, args, err := st.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return executor.QueryContext(withStatement(ctx, st), query, args...)
+}
@@ -76,7 +76,8 @@ func exprToSql(data expr, sl symbol, pt PlaceHolderType) (query string, args []i
 			}
 		}
 		isNull := value == nil
-		isList := isListType(value)
+		sc, isCond := value.(SqlCond)
+		isList := !isNull && !isCond && isListType(value)
 
 		sls := sl.string(isList, isNull)
 		if isList && sl != eq && sl != notEq {
@@ -85,6 +86,14 @@ func exprToSql(data expr, sl symbol, pt PlaceHolderType) (query string, args []i
 		var exprSql string
 		if isNull {
 			exprSql = fmt.Sprintf("%s %s", key, sls)
+		} else if isCond {
+			// 值是表达式，比如 CASE WHEN，直接展开
+			cq, cs, err := sc.ToWhere(pt)
+			if err != nil {
+				return "", nil, err
+			}
+			exprSql = fmt.Sprintf("%s %s %s", key, sls, cq)
+			args = append(args, cs...)
 		} else if isList {
 			vv := reflect.ValueOf(value)
 			var phs []string
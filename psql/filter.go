@@ -0,0 +1,251 @@
+package psql
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FieldType int
+
+const (
+	StringField FieldType = iota
+	IntField
+	FloatField
+	BoolField
+	TimeField
+)
+
+type FilterOp string
+
+const (
+	OpEq      FilterOp = "eq"
+	OpNotEq   FilterOp = "ne"
+	OpLike    FilterOp = "like"
+	OpNotLike FilterOp = "not_like"
+	OpLt      FilterOp = "lt"
+	OpLte     FilterOp = "lte"
+	OpGt      FilterOp = "gt"
+	OpGte     FilterOp = "gte"
+)
+
+// 按后缀匹配，not_like 要在 like 前面
+var filterOps = []FilterOp{OpNotLike, OpLike, OpNotEq, OpEq, OpLte, OpLt, OpGte, OpGt}
+
+var (
+	ErrFilterOpNotAllowed = errors.New("filter operator not allowed")
+	ErrFilterInvalidValue = errors.New("filter value invalid")
+)
+
+type FilterError struct {
+	Key   string
+	Op    FilterOp
+	Value string
+	Err   error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("psql filter: key = %s, op = %s, value = %q: %v", e.Key, e.Op, e.Value, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+type FilterField struct {
+	Column string
+	Type   FieldType
+	// 允许的操作符，为空时只允许 eq
+	Ops []FilterOp
+}
+
+func (ff FilterField) allow(op FilterOp) bool {
+	if len(ff.Ops) == 0 {
+		return op == OpEq
+	}
+	for _, o := range ff.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (ff FilterField) convert(value string) (interface{}, error) {
+	switch ff.Type {
+	case IntField:
+		return strconv.ParseInt(value, 10, 64)
+	case FloatField:
+		return strconv.ParseFloat(value, 64)
+	case BoolField:
+		return strconv.ParseBool(value)
+	case TimeField:
+		return time.Parse(time.RFC3339, value)
+	}
+	return value, nil
+}
+
+// FilterSchema 对外的字段名到字段定义的映射，比如 status=a,b&age_gte=18&name_like=foo
+// eq/ne 的值可以用逗号分隔，生成 IN/NOT IN；同一个 key 出现多次时用 OR 连接；
+// 不在 schema 里的 key 会被忽略，方便和分页、排序参数放在一起
+type FilterSchema map[string]FilterField
+
+// Parse 没有任何条件时返回 nil，Where 会忽略空的条件
+func (fs FilterSchema) Parse(values url.Values) (And, error) {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// 排序，避免每次都不一样
+	sort.Strings(keys)
+
+	var conditions And
+	for _, key := range keys {
+		cond, err := fs.parseKey(key, values[key])
+		if err != nil {
+			return nil, err
+		}
+		if cond != nil {
+			conditions = append(conditions, cond)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	return conditions, nil
+}
+
+func (fs FilterSchema) ParseMap(data map[string]string) (And, error) {
+	values := make(url.Values, len(data))
+	for key, value := range data {
+		values.Set(key, value)
+	}
+	return fs.Parse(values)
+}
+
+func (fs FilterSchema) lookup(key string) (FilterField, FilterOp, bool) {
+	if field, ok := fs[key]; ok {
+		return field, OpEq, true
+	}
+	for _, op := range filterOps {
+		name := strings.TrimSuffix(key, "_"+string(op))
+		if name == key {
+			continue
+		}
+		if field, ok := fs[name]; ok {
+			return field, op, true
+		}
+	}
+	return FilterField{}, "", false
+}
+
+func (fs FilterSchema) parseKey(key string, values []string) (SqlCond, error) {
+	field, op, ok := fs.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	if !field.allow(op) {
+		return nil, &FilterError{Key: key, Op: op, Value: strings.Join(values, ","), Err: ErrFilterOpNotAllowed}
+	}
+
+	if op == OpEq || op == OpNotEq {
+		var list []interface{}
+		for _, value := range values {
+			for _, item := range strings.Split(value, ",") {
+				item = strings.TrimSpace(item)
+				if item == "" {
+					continue
+				}
+				v, err := field.convert(item)
+				if err != nil {
+					return nil, &FilterError{Key: key, Op: op, Value: item, Err: fmt.Errorf("%w: %v", ErrFilterInvalidValue, err)}
+				}
+				list = append(list, v)
+			}
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+
+		var value interface{} = list
+		if len(list) == 1 {
+			value = list[0]
+		}
+		if op == OpEq {
+			return Eq{field.Column: value}, nil
+		}
+		return NotEq{field.Column: value}, nil
+	}
+
+	var conditions Or
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		cond, err := field.toCond(op, value)
+		if err != nil {
+			return nil, &FilterError{Key: key, Op: op, Value: value, Err: fmt.Errorf("%w: %v", ErrFilterInvalidValue, err)}
+		}
+		conditions = append(conditions, cond)
+	}
+
+	switch len(conditions) {
+	case 0:
+		return nil, nil
+	case 1:
+		return conditions[0], nil
+	}
+	return conditions, nil
+}
+
+func (ff FilterField) toCond(op FilterOp, value string) (SqlCond, error) {
+	switch op {
+	case OpLike:
+		return likeCond{column: ff.Column, pattern: "%" + escapeLike(value) + "%"}, nil
+	case OpNotLike:
+		return likeCond{column: ff.Column, pattern: "%" + escapeLike(value) + "%", not: true}, nil
+	}
+
+	v, err := ff.convert(value)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case OpLt:
+		return Lt{ff.Column: v}, nil
+	case OpLte:
+		return Lte{ff.Column: v}, nil
+	case OpGt:
+		return Gt{ff.Column: v}, nil
+	case OpGte:
+		return Gte{ff.Column: v}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// 转义字符用 !，反斜杠在 MySQL 的字符串里本身也要转义，不同数据库的写法不一样
+var likeReplacer = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// likeCond 模式里的 % 和 _ 已经转义，需要带上 ESCAPE 子句
+type likeCond struct {
+	column  string
+	pattern string
+	not     bool
+}
+
+func (lc likeCond) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	op := "LIKE"
+	if lc.not {
+		op = "NOT LIKE"
+	}
+	return fmt.Sprintf("%s %s %s ESCAPE '!'", lc.column, op, pt.Mark()), []interface{}{lc.pattern}, nil
+}
+
+func escapeLike(value string) string {
+	return likeReplacer.Replace(value)
+}
@@ -0,0 +1,101 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type QueryEvent struct {
+	// 通过 Exec、Query 执行时是对应的语句，直接执行 sql 时为 nil
+	Statement SqlStatement
+	// sql 的第一个关键字，比如 SELECT、INSERT
+	Kind     string
+	Query    string
+	Args     []interface{}
+	Start    time.Time
+	Duration time.Duration
+	// 查询语句为 -1
+	RowsAffected int64
+	Err          error
+}
+
+type QueryHook interface {
+	Before(ctx context.Context, event *QueryEvent) context.Context
+	After(ctx context.Context, event *QueryEvent)
+}
+
+type statementKey struct{}
+
+func withStatement(ctx context.Context, st SqlStatement) context.Context {
+	return context.WithValue(ctx, statementKey{}, st)
+}
+
+// HookExecutor 包装任意 Executor，比如 *sql.DB、*sql.Tx，在执行前后调用钩子
+type HookExecutor struct {
+	executor Executor
+	hooks    []QueryHook
+}
+
+func WithHooks(executor Executor, hooks ...QueryHook) *HookExecutor {
+	return &HookExecutor{executor: executor, hooks: hooks}
+}
+
+func (he *HookExecutor) before(ctx context.Context, query string, args []interface{}) (context.Context, *QueryEvent) {
+	st, _ := ctx.Value(statementKey{}).(SqlStatement)
+	event := &QueryEvent{
+		Statement:    st,
+		Kind:         queryKind(query),
+		Query:        query,
+		Args:         args,
+		Start:        time.Now(),
+		RowsAffected: -1,
+	}
+	for _, hook := range he.hooks {
+		ctx = hook.Before(ctx, event)
+	}
+	return ctx, event
+}
+
+func (he *HookExecutor) after(ctx context.Context, event *QueryEvent, err error) {
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	for _, hook := range he.hooks {
+		hook.After(ctx, event)
+	}
+}
+
+func (he *HookExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, event := he.before(ctx, query, args)
+	result, err := he.executor.ExecContext(ctx, query, args...)
+	if err == nil {
+		if affected, aerr := result.RowsAffected(); aerr == nil {
+			event.RowsAffected = affected
+		}
+	}
+	he.after(ctx, event, err)
+	return result, err
+}
+
+func (he *HookExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, event := he.before(ctx, query, args)
+	rows, err := he.executor.QueryContext(ctx, query, args...)
+	he.after(ctx, event, err)
+	return rows, err
+}
+
+func (he *HookExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, event := he.before(ctx, query, args)
+	row := he.executor.QueryRowContext(ctx, query, args...)
+	he.after(ctx, event, row.Err())
+	return row
+}
+
+func queryKind(query string) string {
+	query = strings.TrimSpace(query)
+	if index := strings.IndexAny(query, " \t\n("); index >= 0 {
+		query = query[:index]
+	}
+	return strings.ToUpper(query)
+}
@@ -0,0 +1,121 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CreateIndexStatement struct {
+	Dialect     Dialect
+	IndexName   string
+	TableName   string
+	Columns     []string
+	UniqueValue bool
+	IfNotExist  bool
+}
+
+func NewCreateIndex(dialect Dialect) *CreateIndexStatement {
+	return &CreateIndexStatement{Dialect: dialect}
+}
+
+func (ci *CreateIndexStatement) Name(name string) *CreateIndexStatement {
+	ci.IndexName = name
+	return ci
+}
+
+func (ci *CreateIndexStatement) On(table string, columns ...string) *CreateIndexStatement {
+	ci.TableName = table
+	ci.Columns = append(ci.Columns, columns...)
+	return ci
+}
+
+func (ci *CreateIndexStatement) Unique() *CreateIndexStatement {
+	ci.UniqueValue = true
+	return ci
+}
+
+func (ci *CreateIndexStatement) IfNotExists() *CreateIndexStatement {
+	ci.IfNotExist = true
+	return ci
+}
+
+func (ci *CreateIndexStatement) ToSql() (query string, args []interface{}, err error) {
+	if ci.IndexName == "" || ci.TableName == "" || len(ci.Columns) == 0 {
+		return "", nil, fmt.Errorf("create index sql lack of name, table or column")
+	}
+
+	var sql strings.Builder
+	sql.WriteString("CREATE ")
+	if ci.UniqueValue {
+		sql.WriteString("UNIQUE ")
+	}
+	sql.WriteString("INDEX ")
+	if ci.IfNotExist {
+		if ci.Dialect == MySQL {
+			return "", nil, fmt.Errorf("mysql does not support CREATE INDEX IF NOT EXISTS")
+		}
+		sql.WriteString("IF NOT EXISTS ")
+	}
+	sql.WriteString(fmt.Sprintf("%s ON %s (%s)", ci.IndexName, ci.TableName, strings.Join(ci.Columns, ",")))
+
+	return sql.String(), nil, nil
+}
+
+type DropIndexStatement struct {
+	Dialect   Dialect
+	IndexName string
+	TableName string
+	IfExist   bool
+}
+
+func NewDropIndex(dialect Dialect) *DropIndexStatement {
+	return &DropIndexStatement{Dialect: dialect}
+}
+
+func (di *DropIndexStatement) Name(name string) *DropIndexStatement {
+	di.IndexName = name
+	return di
+}
+
+// On mysql 删除索引需要指定表名
+func (di *DropIndexStatement) On(table string) *DropIndexStatement {
+	di.TableName = table
+	return di
+}
+
+func (di *DropIndexStatement) IfExists() *DropIndexStatement {
+	di.IfExist = true
+	return di
+}
+
+func (di *DropIndexStatement) ToSql() (query string, args []interface{}, err error) {
+	if di.IndexName == "" {
+		return "", nil, fmt.Errorf("drop index sql lack of name")
+	}
+
+	if di.Dialect == MySQL {
+		if di.IfExist {
+			return "", nil, fmt.Errorf("mysql does not support DROP INDEX IF EXISTS")
+		}
+		if di.TableName == "" {
+			return "", nil, fmt.Errorf("mysql drop index sql lack of TableName")
+		}
+		return fmt.Sprintf("DROP INDEX %s ON %s", di.IndexName, di.TableName), nil, nil
+	}
+
+	if di.IfExist {
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s", di.IndexName), nil, nil
+	}
+	return fmt.Sprintf("DROP INDEX %s", di.IndexName), nil, nil
+}
+
+func (ci *CreateIndexStatement) Clone() *CreateIndexStatement {
+	clone := *ci
+	clone.Columns = append([]string(nil), ci.Columns...)
+	return &clone
+}
+
+func (di *DropIndexStatement) Clone() *DropIndexStatement {
+	clone := *di
+	return &clone
+}
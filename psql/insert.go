@@ -11,6 +11,8 @@ type InsertStatement struct {
 	TableName  string
 	Columns    []string
 	Values     [][]interface{}
+	Scope      Eq
+	Shards     map[string]ShardStrategy
 }
 
 func NewInsert(holderType PlaceHolderType) *InsertStatement {
@@ -52,23 +54,37 @@ func (it *InsertStatement) SetMap(data map[string]interface{}) *InsertStatement
 	return it
 }
 
+func (it *InsertStatement) Unscoped() *InsertStatement {
+	it.Scope = nil
+	return it
+}
+
 func (it *InsertStatement) ToSql() (query string, args []interface{}, err error) {
+	columns, values, err := it.scoped()
+	if err != nil {
+		return
+	}
+	table, err := shardInsertTable(it.Shards, it.TableName, columns, values)
+	if err != nil {
+		return
+	}
+
 	var sql strings.Builder
-	_, err = sql.WriteString(fmt.Sprintf("INSERT INTO %s ", it.TableName))
+	_, err = sql.WriteString(fmt.Sprintf("INSERT INTO %s ", table))
 	if err != nil {
 		return
 	}
 
-	if len(it.Columns) > 0 {
-		_, err = sql.WriteString(fmt.Sprintf("(%s)", strings.Join(it.Columns, ",")))
+	if len(columns) > 0 {
+		_, err = sql.WriteString(fmt.Sprintf("(%s)", strings.Join(columns, ",")))
 		if err != nil {
 			return
 		}
 	}
 
 	sql.WriteString(" VALUES ")
-	if len(it.Values) > 0 {
-		for li, list := range it.Values {
+	if len(values) > 0 {
+		for li, list := range values {
 			if li > 0 {
 				_, err = sql.WriteString(",")
 				if err != nil {
@@ -89,5 +105,67 @@ func (it *InsertStatement) ToSql() (query string, args []interface{}, err error)
 		}
 	}
 
-	return sql.String(), args, nil
+	return it.HolderType.replace(sql.String()), args, nil
+}
+
+// scoped 没写作用域的列时自动补上，写了的话值必须和作用域一致
+func (it *InsertStatement) scoped() ([]string, [][]interface{}, error) {
+	if it.Scope == nil {
+		return it.Columns, it.Values, nil
+	}
+	if err := checkScope(it.Scope); err != nil {
+		return nil, nil, err
+	}
+	if len(it.Columns) == 0 {
+		return nil, nil, fmt.Errorf("scoped insert sql lack of column")
+	}
+
+	columns := append([]string(nil), it.Columns...)
+	var extra []string
+	for _, column := range scopeColumns(it.Scope) {
+		index := -1
+		for ci, c := range it.Columns {
+			if c == column {
+				index = ci
+				break
+			}
+		}
+		if index < 0 {
+			extra = append(extra, column)
+			continue
+		}
+		for _, list := range it.Values {
+			if index >= len(list) {
+				return nil, nil, fmt.Errorf("insert value lack of scope column %s", column)
+			}
+			if err := checkScopeValue(it.Scope, column, list[index]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	columns = append(columns, extra...)
+
+	var values [][]interface{}
+	for _, list := range it.Values {
+		row := append([]interface{}(nil), list...)
+		for _, column := range extra {
+			row = append(row, it.Scope[column])
+		}
+		values = append(values, row)
+	}
+	return columns, values, nil
+}
+
+func (it *InsertStatement) Clone() *InsertStatement {
+	clone := *it
+	clone.Columns = append([]string(nil), it.Columns...)
+	clone.Values = nil
+	for _, values := range it.Values {
+		row := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			row = append(row, cloneValue(value))
+		}
+		clone.Values = append(clone.Values, row)
+	}
+	return &clone
 }
@@ -0,0 +1,361 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityOff Severity = iota
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOff:
+		return "off"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	}
+	return ""
+}
+
+type LintRule string
+
+const (
+	RuleLeadingWildcard    LintRule = "leading_wildcard"
+	RuleSelectStar         LintRule = "select_star"
+	RuleMissingLimit       LintRule = "missing_limit"
+	RuleOrDifferentColumns LintRule = "or_different_columns"
+	RuleFunctionOnColumn   LintRule = "function_on_column"
+)
+
+type Finding struct {
+	Rule     LintRule
+	Severity Severity
+	Message  string
+}
+
+type LintError struct {
+	Findings []Finding
+}
+
+func (e *LintError) Error() string {
+	var messages []string
+	for _, finding := range e.Findings {
+		messages = append(messages, fmt.Sprintf("[%s %s] %s", finding.Severity, finding.Rule, finding.Message))
+	}
+	return fmt.Sprintf("psql lint: %s", strings.Join(messages, "; "))
+}
+
+type LinterConfig struct {
+	severities map[LintRule]Severity
+	indexed    map[string]bool
+	failOn     Severity
+}
+
+type LintOption func(cfg *LinterConfig)
+
+func RuleSeverity(rule LintRule, severity Severity) LintOption {
+	return func(cfg *LinterConfig) {
+		cfg.severities[rule] = severity
+	}
+}
+
+// IndexedColumns 只检查这些列上有没有包函数，不设置时检查所有列
+func IndexedColumns(columns ...string) LintOption {
+	return func(cfg *LinterConfig) {
+		for _, column := range columns {
+			cfg.indexed[strings.ToLower(column)] = true
+		}
+	}
+}
+
+// FailOn Check 时大于等于这个级别的问题会返回错误
+func FailOn(severity Severity) LintOption {
+	return func(cfg *LinterConfig) {
+		cfg.failOn = severity
+	}
+}
+
+type Linter struct {
+	cfg *LinterConfig
+}
+
+func NewLinter(options ...LintOption) *Linter {
+	cfg := &LinterConfig{
+		severities: map[LintRule]Severity{
+			RuleLeadingWildcard:    SeverityWarn,
+			RuleSelectStar:         SeverityWarn,
+			RuleMissingLimit:       SeverityWarn,
+			RuleOrDifferentColumns: SeverityInfo,
+			RuleFunctionOnColumn:   SeverityWarn,
+		},
+		indexed: make(map[string]bool),
+		failOn:  SeverityError,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	return &Linter{cfg: cfg}
+}
+
+func (l *Linter) Lint(st SqlStatement) []Finding {
+	var findings []Finding
+	report := func(rule LintRule, format string, args ...interface{}) {
+		severity := l.cfg.severities[rule]
+		if severity == SeverityOff {
+			return
+		}
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var wheres []SqlCond
+	switch s := st.(type) {
+	case *SelectStatement:
+		l.lintColumns(s, report)
+		wheres = s.Wheres
+	case *UpdateStatement:
+		wheres = s.Wheres
+	case *DeleteStatement:
+		wheres = s.Wheres
+	}
+	for _, where := range wheres {
+		l.lintCond(where, report)
+	}
+
+	return findings
+}
+
+// Check 有大于等于 FailOn 级别的问题时返回 *LintError
+func (l *Linter) Check(st SqlStatement) error {
+	var failed []Finding
+	for _, finding := range l.Lint(st) {
+		if finding.Severity >= l.cfg.failOn {
+			failed = append(failed, finding)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &LintError{Findings: failed}
+}
+
+var aggregatePrefixes = []string{"COUNT(", "SUM(", "MAX(", "MIN(", "AVG("}
+
+func (l *Linter) lintColumns(st *SelectStatement, report func(rule LintRule, format string, args ...interface{})) {
+	onlyAggregate := len(st.Columns) > 0
+	for _, column := range st.Columns {
+		query, _, err := column.ToWhere(st.HolderType)
+		if err != nil {
+			continue
+		}
+		if query == "*" || strings.HasSuffix(query, ".*") {
+			report(RuleSelectStar, "select %s, list the columns instead", query)
+		}
+
+		upper := strings.ToUpper(query)
+		isAggregate := false
+		for _, prefix := range aggregatePrefixes {
+			if strings.HasPrefix(upper, prefix) {
+				isAggregate = true
+				break
+			}
+		}
+		onlyAggregate = onlyAggregate && isAggregate
+	}
+
+	// 只有聚合函数且没有 GROUP BY 时只会返回一行
+	if st.LimitValue == nil && !(onlyAggregate && len(st.GroupBys) == 0) {
+		report(RuleMissingLimit, "select from %s without limit", st.TableName)
+	}
+}
+
+func (l *Linter) lintCond(cond SqlCond, report func(rule LintRule, format string, args ...interface{})) {
+	switch c := cond.(type) {
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			l.lintCond(q, report)
+		case map[string]interface{}:
+			l.lintCond(Eq(q), report)
+		case string:
+			l.lintFunction(q, report)
+		}
+	case And:
+		for _, item := range c {
+			l.lintCond(item, report)
+		}
+	case Or:
+		columns := make(map[string]bool)
+		for _, item := range c {
+			for _, column := range condColumns(item) {
+				columns[column] = true
+			}
+			l.lintCond(item, report)
+		}
+		if len(columns) > 1 {
+			var list []string
+			for column := range columns {
+				list = append(list, column)
+			}
+			sort.Strings(list)
+			report(RuleOrDifferentColumns, "or across different columns %s can not use a single index", strings.Join(list, ","))
+		}
+	case Like:
+		l.lintLike(expr(c), report)
+	case NotLike:
+		l.lintLike(expr(c), report)
+	case likeCond:
+		// FilterSchema 生成的模式已经转义，开头的 % 一定是通配符
+		l.lintLike(expr{c.column: c.pattern}, report)
+	}
+
+	if data, ok := condExpr(cond); ok {
+		for _, key := range sortedKeys(data) {
+			l.lintFunction(key, report)
+		}
+	}
+}
+
+func (l *Linter) lintLike(data expr, report func(rule LintRule, format string, args ...interface{})) {
+	for _, key := range sortedKeys(data) {
+		if s, ok := data[key].(string); ok && strings.HasPrefix(s, "%") {
+			report(RuleLeadingWildcard, "%s like %q starts with wildcard, index can not be used", key, s)
+		}
+	}
+}
+
+var functionPattern = regexp.MustCompile(`([A-Za-z_]+)\(\s*([A-Za-z_][A-Za-z0-9_.]*)`)
+
+func (l *Linter) lintFunction(query string, report func(rule LintRule, format string, args ...interface{})) {
+	for _, match := range functionPattern.FindAllStringSubmatch(query, -1) {
+		column := strings.ToLower(match[2])
+		if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+			column = column[dot+1:]
+		}
+		if len(l.cfg.indexed) > 0 && !l.cfg.indexed[column] {
+			continue
+		}
+		report(RuleFunctionOnColumn, "function %s wraps column %s, index can not be used", match[1], match[2])
+	}
+}
+
+func sortedKeys(data expr) []string {
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func condExpr(cond SqlCond) (expr, bool) {
+	switch c := cond.(type) {
+	case Eq:
+		return expr(c), true
+	case NotEq:
+		return expr(c), true
+	case Like:
+		return expr(c), true
+	case NotLike:
+		return expr(c), true
+	case Lt:
+		return expr(c), true
+	case Lte:
+		return expr(c), true
+	case Gt:
+		return expr(c), true
+	case Gte:
+		return expr(c), true
+	}
+	return nil, false
+}
+
+// condColumns 条件里用到的列，字符串条件无法解析，当作一个单独的列
+func condColumns(cond SqlCond) []string {
+	switch c := cond.(type) {
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			return condColumns(q)
+		case map[string]interface{}:
+			return condColumns(Eq(q))
+		case string:
+			return []string{q}
+		}
+	case And:
+		var columns []string
+		for _, item := range c {
+			columns = append(columns, condColumns(item)...)
+		}
+		return columns
+	case Or:
+		var columns []string
+		for _, item := range c {
+			columns = append(columns, condColumns(item)...)
+		}
+		return columns
+	case likeCond:
+		return []string{c.column}
+	}
+
+	data, ok := condExpr(cond)
+	if !ok {
+		return nil
+	}
+	var columns []string
+	for key := range data {
+		columns = append(columns, key)
+	}
+	return columns
+}
+
+// LintExecutor 开发环境使用，通过 Exec、Query 执行的语句检查不通过时直接返回错误
+type LintExecutor struct {
+	executor Executor
+	linter   *Linter
+}
+
+func WithLinter(executor Executor, linter *Linter) *LintExecutor {
+	return &LintExecutor{executor: executor, linter: linter}
+}
+
+func (le *LintExecutor) check(ctx context.Context) error {
+	st, ok := ctx.Value(statementKey{}).(SqlStatement)
+	if !ok {
+		return nil
+	}
+	return le.linter.Check(st)
+}
+
+func (le *LintExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := le.check(ctx); err != nil {
+		return nil, err
+	}
+	return le.executor.ExecContext(ctx, query, args...)
+}
+
+func (le *LintExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := le.check(ctx); err != nil {
+		return nil, err
+	}
+	return le.executor.QueryContext(ctx, query, args...)
+}
+
+func (le *LintExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	// *sql.Row 没办法构造错误，只能交给底层执行
+	return le.executor.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,120 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+
+	"github.com/yongpi/putil/psql"
+)
+
+type locker interface {
+	lock(ctx context.Context, conn *sql.Conn) error
+	unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+func newLocker(dialect psql.Dialect, builder psql.SqlBuilder, name string, ttl time.Duration) locker {
+	switch dialect {
+	case psql.MySQL:
+		return &mysqlLocker{name: name}
+	case psql.Postgres:
+		return &postgresLocker{key: int64(crc32.ChecksumIEEE([]byte(name)))}
+	}
+	return &tableLocker{builder: builder, table: name, ttl: ttl, owner: lockOwner()}
+}
+
+// mysqlLocker GET_LOCK 是会话级别的，所以加锁和解锁必须在同一个连接上
+type mysqlLocker struct {
+	name string
+}
+
+func (l *mysqlLocker) lock(ctx context.Context, conn *sql.Conn) error {
+	var ok sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, -1)", l.name).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if ok.Int64 != 1 {
+		return fmt.Errorf("%w, name = %s", ErrLocked, l.name)
+	}
+	return nil
+}
+
+func (l *mysqlLocker) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", l.name)
+	return err
+}
+
+type postgresLocker struct {
+	key int64
+}
+
+func (l *postgresLocker) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("SELECT pg_advisory_lock(%d)", l.key))
+	return err
+}
+
+func (l *postgresLocker) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("SELECT pg_advisory_unlock(%d)", l.key))
+	return err
+}
+
+// tableLocker 没有 advisory lock 的数据库用锁表，插入成功就是拿到锁，主键冲突说明别的实例在迁移；
+// 持有者崩溃后锁不会释放，超过 ttl 的锁会被删掉重新抢
+type tableLocker struct {
+	builder psql.SqlBuilder
+	table   string
+	ttl     time.Duration
+	owner   string
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+func (l *tableLocker) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := psql.Exec(ctx, conn, l.builder.CreateTable(l.table).
+		IfNotExists().
+		Column(
+			psql.NewColumnDef("id", psql.IntType).NotNull(),
+			psql.NewColumnDef("owner", psql.VarcharType(255)).NotNull(),
+			psql.NewColumnDef("locked_at", psql.BigIntType).NotNull(),
+		).
+		PrimaryKey("id"))
+	if err != nil {
+		return err
+	}
+
+	if err = l.insert(ctx, conn); err == nil {
+		return nil
+	}
+
+	// 删掉过期的锁再抢一次
+	if l.ttl > 0 {
+		expired := time.Now().Add(-l.ttl).Unix()
+		_, derr := psql.Exec(ctx, conn, l.builder.Delete(l.table).Where(psql.Eq{"id": 1}).Where(psql.Lt{"locked_at": expired}))
+		if derr == nil {
+			if err = l.insert(ctx, conn); err == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w, table = %s, err = %v", ErrLocked, l.table, err)
+}
+
+func (l *tableLocker) insert(ctx context.Context, conn *sql.Conn) error {
+	_, err := psql.Exec(ctx, conn, l.builder.Insert(l.table).
+		Column("id", "owner", "locked_at").
+		Value(1, l.owner, time.Now().Unix()))
+	return err
+}
+
+// unlock 只删除自己持有的锁，避免锁过期被别人抢走后误删
+func (l *tableLocker) unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := psql.Exec(ctx, conn, l.builder.Delete(l.table).Where(psql.Eq{"id": 1, "owner": l.owner}))
+	return err
+}
@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yongpi/putil/psql"
+)
+
+type MigrateFunc func(ctx context.Context, executor psql.Executor) error
+
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSql    string
+	DownSql  string
+	Up       MigrateFunc
+	Down     MigrateFunc
+	Checksum string
+}
+
+func (m *Migration) hasUp() bool {
+	return m.Up != nil || strings.TrimSpace(m.UpSql) != ""
+}
+
+func (m *Migration) hasDown() bool {
+	return m.Down != nil || m.DownSql != ""
+}
+
+func (m *Migration) run(ctx context.Context, executor psql.Executor, up bool) error {
+	fn, query := m.Up, m.UpSql
+	if !up {
+		fn, query = m.Down, m.DownSql
+	}
+
+	if fn != nil {
+		return fn(ctx, executor)
+	}
+	// mysql 默认不能一次执行多条语句，拆开逐条执行
+	for _, statement := range splitStatements(query) {
+		if _, err := executor.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 按 ; 拆分 sql 文件，跳过引号、注释和 postgres 的 $tag$ 里的 ;，只有注释的部分忽略
+// 不支持 mysql 客户端的 DELIMITER，存储过程需要用 MigrateFunc
+func splitStatements(query string) []string {
+	var statements []string
+	start, hasContent := 0, false
+	flush := func(end int) {
+		if hasContent {
+			statements = append(statements, strings.TrimSpace(query[start:end]))
+		}
+		start, hasContent = end+1, false
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ';':
+			flush(i)
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i, c)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+			continue
+		case c == '$':
+			i = skipDollarQuoted(query, i)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+		hasContent = true
+	}
+	flush(len(query))
+	return statements
+}
+
+// skipQuoted 返回结束引号的位置，两个连续的引号和反斜杠转义的引号不算结束
+func skipQuoted(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(query)
+}
+
+// skipDollarQuoted $tag$ ... $tag$，不是 tag 的 $ 原样返回，比如 $1
+func skipDollarQuoted(query string, start int) int {
+	end := strings.IndexByte(query[start+1:], '$')
+	if end < 0 {
+		return start
+	}
+	tag := query[start : start+end+2]
+	for _, r := range tag[1 : len(tag)-1] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return start
+		}
+	}
+	if index := strings.Index(query[start+len(tag):], tag); index >= 0 {
+		return start + len(tag) + index + len(tag) - 1
+	}
+	return len(query)
+}
+
+// migrationChecksum up 和 down 的内容都算进去，down 被修改也能发现
+func migrationChecksum(up, down string) string {
+	hash := sha256.New()
+	hash.Write([]byte(up))
+	if down != "" {
+		hash.Write([]byte{0})
+		hash.Write([]byte(down))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// LoadFS 读取 dir 下 <version>_<name>.up.sql 和 <version>_<name>.down.sql 文件，可以直接传 embed.FS
+func LoadFS(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		fileName := entry.Name()
+		var up bool
+		var base string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			up = true
+			base = strings.TrimSuffix(fileName, ".up.sql")
+		case strings.HasSuffix(fileName, ".down.sql"):
+			base = strings.TrimSuffix(fileName, ".down.sql")
+		default:
+			continue
+		}
+
+		vs, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(vs, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file name has wrong version, file = %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			migrations[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration version %d has different name, %s and %s", version, m.Name, name)
+		}
+
+		if up {
+			m.UpSql = string(content)
+		} else {
+			m.DownSql = string(content)
+		}
+	}
+
+	var list []*Migration
+	for _, m := range migrations {
+		if m.UpSql == "" {
+			return nil, fmt.Errorf("migration version %d lack of up file", m.Version)
+		}
+		m.Checksum = migrationChecksum(m.UpSql, m.DownSql)
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+
+	return list, nil
+}
@@ -0,0 +1,335 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/yongpi/putil/psql"
+)
+
+var (
+	ErrLocked           = errors.New("migration is locked by another instance")
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrNoDown           = errors.New("migration has no down")
+)
+
+type Config struct {
+	dialect  psql.Dialect
+	table    string
+	lockName string
+	lockTTL  time.Duration
+}
+
+type Option func(cfg *Config)
+
+func Dialect(dialect psql.Dialect) Option {
+	return func(cfg *Config) {
+		cfg.dialect = dialect
+	}
+}
+
+func Table(table string) Option {
+	return func(cfg *Config) {
+		cfg.table = table
+	}
+}
+
+// LockName mysql 是 GET_LOCK 的名字，postgres 会转成 advisory lock 的 key，其他数据库是锁表的表名
+func LockName(lockName string) Option {
+	return func(cfg *Config) {
+		cfg.lockName = lockName
+	}
+}
+
+// LockTTL 锁表的锁超过这个时间没有释放就认为持有者已经崩溃，可以被抢占，需要比最长的迁移时间长
+func LockTTL(ttl time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.lockTTL = ttl
+	}
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Checksum  string
+	// 已经执行过，但是文件内容变了
+	Modified bool
+}
+
+type record struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt int64
+}
+
+type Migrator struct {
+	db         *sql.DB
+	cfg        *Config
+	builder    psql.SqlBuilder
+	migrations map[int64]*Migration
+}
+
+func New(db *sql.DB, options ...Option) *Migrator {
+	cfg := &Config{
+		dialect:  psql.MySQL,
+		table:    "schema_migrations",
+		lockName: "schema_migrations_lock",
+		lockTTL:  time.Hour,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	return &Migrator{
+		db:         db,
+		cfg:        cfg,
+		builder:    psql.NewSqlBuilder(cfg.dialect.PlaceHolder()).WithDialect(cfg.dialect),
+		migrations: make(map[int64]*Migration),
+	}
+}
+
+func (m *Migrator) Add(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		// 没有 up 的迁移会被直接记录成已经执行
+		if !migration.hasUp() {
+			return fmt.Errorf("migration version %d lack of up", migration.Version)
+		}
+		if _, ok := m.migrations[migration.Version]; ok {
+			return fmt.Errorf("migration version %d already exists", migration.Version)
+		}
+		m.migrations[migration.Version] = migration
+	}
+	return nil
+}
+
+func (m *Migrator) Register(version int64, name string, up, down MigrateFunc) error {
+	return m.Add(&Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+func (m *Migrator) LoadFS(fsys fs.FS, dir string) error {
+	migrations, err := LoadFS(fsys, dir)
+	if err != nil {
+		return err
+	}
+	return m.Add(migrations...)
+}
+
+func (m *Migrator) sorted() []*Migration {
+	var list []*Migration
+	for _, migration := range m.migrations {
+		list = append(list, migration)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// Up 按版本顺序执行所有没有执行过的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]record) error {
+		for _, migration := range m.sorted() {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本倒序回滚最近执行的 n 个迁移
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n < 0 {
+		return fmt.Errorf("down count must not be negative, n = %d", n)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]record) error {
+		for _, migration := range m.latestApplied(applied, n) {
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Redo 回滚最近执行的迁移再重新执行
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]record) error {
+		for _, migration := range m.latestApplied(applied, 1) {
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Status
+	for _, migration := range m.sorted() {
+		status := Status{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum}
+		if r, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = time.Unix(r.appliedAt, 0)
+			status.Modified = migration.Checksum != "" && r.checksum != migration.Checksum
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+func (m *Migrator) latestApplied(applied map[int64]record, n int) []*Migration {
+	var versions []int64
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+	if n < len(versions) {
+		versions = versions[:n]
+	}
+
+	var list []*Migration
+	for _, version := range versions {
+		migration, ok := m.migrations[version]
+		if !ok {
+			// 数据库里有记录但是找不到迁移，只能用记录构造一个没有 down 的，apply 时报错
+			migration = &Migration{Version: version, Name: applied[version].name}
+		}
+		list = append(list, migration)
+	}
+	return list
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]record) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	locker := newLocker(m.cfg.dialect, m.builder, m.cfg.lockName, m.cfg.lockTTL)
+	if err = locker.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if uerr := locker.unlock(ctx, conn); uerr != nil && err == nil {
+			err = uerr
+		}
+	}()
+
+	if err = m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for version, r := range applied {
+		migration, ok := m.migrations[version]
+		if ok && migration.Checksum != "" && r.checksum != migration.Checksum {
+			return fmt.Errorf("%w, version = %d", ErrChecksumMismatch, version)
+		}
+	}
+
+	return fn(conn, applied)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := psql.Exec(ctx, conn, m.builder.CreateTable(m.cfg.table).
+		IfNotExists().
+		Column(
+			psql.NewColumnDef("version", psql.BigIntType).NotNull(),
+			psql.NewColumnDef("name", psql.VarcharType(255)).NotNull(),
+			psql.NewColumnDef("checksum", psql.VarcharType(64)).NotNull(),
+			psql.NewColumnDef("applied_at", psql.BigIntType).NotNull(),
+		).
+		PrimaryKey("version"))
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	rows, err := psql.Query(ctx, conn, m.builder.Select("version", "name", "checksum", "applied_at").From(m.cfg.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]record)
+	for rows.Next() {
+		var r record
+		if err = rows.Scan(&r.version, &r.name, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[r.version] = r
+	}
+	return applied, rows.Err()
+}
+
+// apply mysql 的 DDL 会隐式提交，事务没有意义，其他数据库迁移和记录在同一个事务里
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration *Migration, up bool) error {
+	if !up && !migration.hasDown() {
+		return fmt.Errorf("%w, version = %d", ErrNoDown, migration.Version)
+	}
+
+	var bookkeeping psql.SqlStatement
+	if up {
+		bookkeeping = m.builder.Insert(m.cfg.table).
+			Column("version", "name", "checksum", "applied_at").
+			Value(migration.Version, migration.Name, migration.Checksum, time.Now().Unix())
+	} else {
+		bookkeeping = m.builder.Delete(m.cfg.table).Where(psql.Eq{"version": migration.Version})
+	}
+
+	if m.cfg.dialect == psql.MySQL {
+		if err := migration.run(ctx, conn, up); err != nil {
+			return fmt.Errorf("migration version %d failed, err = %w", migration.Version, err)
+		}
+		_, err := psql.Exec(ctx, conn, bookkeeping)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = migration.run(ctx, tx, up); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("migration version %d failed, err = %w", migration.Version, err)
+	}
+	if _, err = psql.Exec(ctx, tx, bookkeeping); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OrderDirection int
+
+const (
+	AscOrder OrderDirection = iota
+	DescOrder
+)
+
+func (od OrderDirection) string() string {
+	switch od {
+	case AscOrder:
+		return "ASC"
+	case DescOrder:
+		return "DESC"
+	}
+	return ""
+}
+
+type NullsOrder int
+
+const (
+	NullsDefault NullsOrder = iota
+	NullsFirstOrder
+	NullsLastOrder
+)
+
+// string mysql 不支持 NULLS FIRST/LAST，统一先按 IS NULL 排序，三种数据库都能用
+func (no NullsOrder) string() string {
+	switch no {
+	case NullsFirstOrder:
+		return "DESC"
+	case NullsLastOrder:
+		return "ASC"
+	}
+	return ""
+}
+
+type OrderTerm struct {
+	Column    string
+	Direction OrderDirection
+	Nulls     NullsOrder
+}
+
+func Asc(column string) OrderTerm {
+	return OrderTerm{Column: column, Direction: AscOrder}
+}
+
+func Desc(column string) OrderTerm {
+	return OrderTerm{Column: column, Direction: DescOrder}
+}
+
+func (ot OrderTerm) NullsFirst() OrderTerm {
+	ot.Nulls = NullsFirstOrder
+	return ot
+}
+
+func (ot OrderTerm) NullsLast() OrderTerm {
+	ot.Nulls = NullsLastOrder
+	return ot
+}
+
+func (ot OrderTerm) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if ot.Column == "" {
+		return "", nil, fmt.Errorf("order term lack of column")
+	}
+	query = fmt.Sprintf("%s %s", ot.Column, ot.Direction.string())
+	if ot.Nulls != NullsDefault {
+		query = fmt.Sprintf("%s IS NULL %s, %s", ot.Column, ot.Nulls.string(), query)
+	}
+	return query, nil, nil
+}
+
+// SortWhitelist 用户可见的排序 key 到列名的映射，不在映射里的 key 直接报错，避免把外部输入拼进 sql
+type SortWhitelist map[string]string
+
+func (sw SortWhitelist) Order(key string, desc bool) (OrderTerm, error) {
+	column, ok := sw[key]
+	if !ok {
+		return OrderTerm{}, fmt.Errorf("sort key is not allowed, key = %q", key)
+	}
+	if desc {
+		return Desc(column), nil
+	}
+	return Asc(column), nil
+}
+
+// Parse 解析 "-created_at,name" 这样的排序参数，前缀 - 表示倒序，+ 或者没有前缀表示正序
+func (sw SortWhitelist) Parse(sort string) ([]OrderTerm, error) {
+	var orders []OrderTerm
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		desc := false
+		switch key[0] {
+		case '-':
+			desc = true
+			key = key[1:]
+		case '+':
+			key = key[1:]
+		}
+
+		order, err := sw.Order(key, desc)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,190 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+type ReplicaStrategy int
+
+const (
+	RoundRobin ReplicaStrategy = iota
+	Random
+	// LeastInFlight 按正在执行的查询数选择，查询返回 rows 之后就不再计数，没有关闭的 rows 不算
+	LeastInFlight
+)
+
+type RouterConfig struct {
+	strategy    ReplicaStrategy
+	maxFailures int64
+	retryAfter  time.Duration
+}
+
+type RouterOption func(cfg *RouterConfig)
+
+func Strategy(strategy ReplicaStrategy) RouterOption {
+	return func(cfg *RouterConfig) {
+		cfg.strategy = strategy
+	}
+}
+
+// MaxFailures 从库连续失败多少次后标记为不可用
+func MaxFailures(maxFailures int64) RouterOption {
+	return func(cfg *RouterConfig) {
+		cfg.maxFailures = maxFailures
+	}
+}
+
+// RetryAfter 从库标记为不可用之后，多久再重新尝试
+func RetryAfter(retryAfter time.Duration) RouterOption {
+	return func(cfg *RouterConfig) {
+		cfg.retryAfter = retryAfter
+	}
+}
+
+type replica struct {
+	db        *sql.DB
+	failures  atomic.Int64
+	downUntil atomic.Int64
+	// 经过 router 正在执行的查询数，db.Stats().InUse 会把空闲事务、没关闭的 rows 和别的调用方都算进去
+	// *sql.Rows 没办法感知关闭，QueryContext 返回时就减一，读取 rows 的时间不算
+	inFlight atomic.Int64
+}
+
+func (r *replica) healthy(now time.Time) bool {
+	return r.downUntil.Load() <= now.UnixNano()
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary 写完马上读的场景，强制这个 ctx 下的查询走主库
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+func isForcePrimary(ctx context.Context) bool {
+	force, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return force
+}
+
+// Router 读写分离，SelectStatement 走从库，其他语句和事务走主库
+type Router struct {
+	primary  *sql.DB
+	replicas []*replica
+	cfg      *RouterConfig
+	next     atomic.Uint64
+}
+
+func NewRouter(primary *sql.DB, replicas []*sql.DB, options ...RouterOption) *Router {
+	cfg := &RouterConfig{
+		strategy:    RoundRobin,
+		maxFailures: 3,
+		retryAfter:  30 * time.Second,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	router := &Router{primary: primary, cfg: cfg}
+	for _, db := range replicas {
+		router.replicas = append(router.replicas, &replica{db: db})
+	}
+	return router
+}
+
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+func (r *Router) Exec(ctx context.Context, st SqlStatement) (sql.Result, error) {
+	return Exec(ctx, r.primary, st)
+}
+
+func (r *Router) Query(ctx context.Context, st SqlStatement) (*sql.Rows, error) {
+	if _, ok := st.(*SelectStatement); !ok || isForcePrimary(ctx) {
+		return Query(ctx, r.primary, st)
+	}
+
+	rep := r.pick()
+	if rep == nil {
+		return Query(ctx, r.primary, st)
+	}
+
+	rep.inFlight.Add(1)
+	rows, err := Query(ctx, rep.db, st)
+	rep.inFlight.Add(-1)
+	if r.report(ctx, rep, err) {
+		// 从库出错时回退到主库，调用方不用感知从库故障
+		return Query(ctx, r.primary, st)
+	}
+	return rows, err
+}
+
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// pick 所有从库都不可用时返回 nil，走主库
+func (r *Router) pick() *replica {
+	now := time.Now()
+	var healthy []*replica
+	for _, rep := range r.replicas {
+		if rep.healthy(now) {
+			healthy = append(healthy, rep)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	switch r.cfg.strategy {
+	case Random:
+		return healthy[rand.Intn(len(healthy))]
+	case LeastInFlight:
+		best := healthy[0]
+		bestInFlight := best.inFlight.Load()
+		for _, rep := range healthy[1:] {
+			if inFlight := rep.inFlight.Load(); inFlight < bestInFlight {
+				best, bestInFlight = rep, inFlight
+			}
+		}
+		return best
+	}
+	return healthy[int((r.next.Add(1)-1)%uint64(len(healthy)))]
+}
+
+// report 记录从库的执行结果，返回 true 表示从库连接出错，需要回退到主库
+func (r *Router) report(ctx context.Context, rep *replica, err error) bool {
+	if err == nil {
+		rep.failures.Store(0)
+		return false
+	}
+	// 调用方取消的不算从库的问题，语法错误、约束错误这类在主库执行也一样会失败
+	if ctx.Err() != nil || !isConnError(err) {
+		return false
+	}
+	if rep.failures.Add(1) >= r.cfg.maxFailures {
+		rep.failures.Store(0)
+		rep.downUntil.Store(time.Now().Add(r.cfg.retryAfter).UnixNano())
+	}
+	return true
+}
+
+// isConnError 连接不可用、网络错误、读写超时才认为是从库的问题
+func isConnError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,139 @@
+package psql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// checkScope 作用域必须有值，nil 和列表都不允许，避免租户 id 没赋值就把所有数据查出来
+func checkScope(scope Eq) error {
+	if len(scope) == 0 {
+		return fmt.Errorf("scope is empty")
+	}
+	for column, value := range scope {
+		if value == nil {
+			return fmt.Errorf("scope value can not be nil, column = %s", column)
+		}
+		if _, ok := value.(SqlCond); ok || isListType(value) {
+			return fmt.Errorf("scope value must be a single value, column = %s, value = %#v", column, value)
+		}
+	}
+	return nil
+}
+
+func scopeWheres(scope Eq, wheres []SqlCond) ([]SqlCond, error) {
+	if scope == nil {
+		return wheres, nil
+	}
+	if err := checkScope(scope); err != nil {
+		return nil, err
+	}
+	return append(groupConds(wheres), scope), nil
+}
+
+// qualifyScope 和软删除的列一样用表名或者别名限定，join 的表有同名列时不会有歧义
+func qualifyScope(table string, scope Eq) Eq {
+	qualified := make(Eq, len(scope))
+	for column, value := range scope {
+		qualified[qualifyColumn(table, column)] = value
+	}
+	return qualified
+}
+
+// parenCond 给条件加上括号，避免 Where("a = ? OR b = ?") 和后面追加的条件优先级混在一起
+type parenCond struct {
+	cond SqlCond
+}
+
+func (pc parenCond) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	query, args, err = pc.cond.ToWhere(pt)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("(%s)", query), args, nil
+}
+
+// groupConds 在用户的条件后面追加作用域、软删除等条件前调用，字符串、表达式这类无法确定优先级的条件都加上括号
+func groupConds(wheres []SqlCond) []SqlCond {
+	if wheres == nil {
+		return nil
+	}
+	groups := make([]SqlCond, 0, len(wheres))
+	for _, where := range wheres {
+		if !isSafeCond(where) {
+			where = parenCond{cond: where}
+		}
+		groups = append(groups, where)
+	}
+	return groups
+}
+
+func isSafeCond(cond SqlCond) bool {
+	switch c := cond.(type) {
+	case parenCond:
+		return true
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			return isSafeCond(q)
+		case map[string]interface{}:
+			return true
+		}
+		return false
+	case And:
+		// 多个条件时会加上括号
+		return len(c) > 1 || (len(c) == 1 && isSafeCond(c[0]))
+	case Or:
+		return len(c) > 1 || (len(c) == 1 && isSafeCond(c[0]))
+	}
+	_, ok := condExpr(cond)
+	return ok
+}
+
+// checkScopeValue 语句里显式写了作用域的列，值必须和作用域一致
+func checkScopeValue(scope Eq, column string, value interface{}) error {
+	sv, ok := scope[column]
+	if !ok || scopeValueEqual(sv, value) {
+		return nil
+	}
+	return fmt.Errorf("value bypass scope, column = %s, scope = %#v, value = %#v", column, sv, value)
+}
+
+func scopeColumns(scope Eq) []string {
+	var columns []string
+	for column := range scope {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// scopeValueEqual 数字按值比较，作用域是 int 时写 int64 的同一个值也算一致
+func scopeValueEqual(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isIntKind(av.Kind()) && isIntKind(bv.Kind()):
+		return av.Int() == bv.Int()
+	case isUintKind(av.Kind()) && isUintKind(bv.Kind()):
+		return av.Uint() == bv.Uint()
+	case isIntKind(av.Kind()) && isUintKind(bv.Kind()):
+		return av.Int() >= 0 && uint64(av.Int()) == bv.Uint()
+	case isUintKind(av.Kind()) && isIntKind(bv.Kind()):
+		return bv.Int() >= 0 && av.Uint() == uint64(bv.Int())
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return av.String() == bv.String()
+	}
+	return false
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
@@ -6,15 +6,22 @@ import (
 )
 
 type SelectStatement struct {
-	HolderType  PlaceHolderType
-	TableName   string
-	Columns     []string
+	HolderType    PlaceHolderType
+	TableName     string
+	DistinctValue bool
+	// 普通列和表达式列按添加的顺序放在一起，Scan 的顺序和这里一致
+	Columns     []SqlCond
 	Wheres      []SqlCond
 	OrderBys    []SqlCond
 	LimitValue  *int64
 	OffsetValue *int64
 	Joins       []SqlCond
 	GroupBys    []SqlCond
+	Windows     []SqlCond
+	// 软删除的列，ToSql 时自动加上 IS NULL 条件
+	SoftDeleteColumn string
+	Scope            Eq
+	Shards           map[string]ShardStrategy
 }
 
 func NewSelect(holderType PlaceHolderType) *SelectStatement {
@@ -22,10 +29,22 @@ func NewSelect(holderType PlaceHolderType) *SelectStatement {
 }
 
 func (st *SelectStatement) Column(columns ...string) *SelectStatement {
+	for _, column := range columns {
+		st.Columns = append(st.Columns, SqlParam{query: column})
+	}
+	return st
+}
+
+func (st *SelectStatement) ColumnExpr(columns ...SqlCond) *SelectStatement {
 	st.Columns = append(st.Columns, columns...)
 	return st
 }
 
+func (st *SelectStatement) Distinct() *SelectStatement {
+	st.DistinctValue = true
+	return st
+}
+
 func (st *SelectStatement) From(table string) *SelectStatement {
 	st.TableName = table
 	return st
@@ -38,6 +57,19 @@ func (st *SelectStatement) OrderBy(orderBys ...string) *SelectStatement {
 	return st
 }
 
+// OrderByTerms 比如 OrderByTerms(Asc("created_at"), Desc("id").NullsLast())
+func (st *SelectStatement) OrderByTerms(terms ...OrderTerm) *SelectStatement {
+	for _, term := range terms {
+		st.OrderBys = append(st.OrderBys, term)
+	}
+	return st
+}
+
+func (st *SelectStatement) OrderByExpr(orderBys ...SqlCond) *SelectStatement {
+	st.OrderBys = append(st.OrderBys, orderBys...)
+	return st
+}
+
 func (st *SelectStatement) GroupBy(groupBys ...string) *SelectStatement {
 	for _, groupBy := range groupBys {
 		st.GroupBys = append(st.GroupBys, SqlParam{query: groupBy})
@@ -45,6 +77,23 @@ func (st *SelectStatement) GroupBy(groupBys ...string) *SelectStatement {
 	return st
 }
 
+func (st *SelectStatement) Window(name string, spec *WindowSpec) *SelectStatement {
+	st.Windows = append(st.Windows, namedWindow{name: name, spec: spec})
+	return st
+}
+
+// Unscoped 显式去掉作用域，比如跨租户的后台查询
+func (st *SelectStatement) Unscoped() *SelectStatement {
+	st.Scope = nil
+	return st
+}
+
+// WithDeleted 软删除模式下也查出已经删除的数据
+func (st *SelectStatement) WithDeleted() *SelectStatement {
+	st.SoftDeleteColumn = ""
+	return st
+}
+
 func (st *SelectStatement) Limit(limit int64) *SelectStatement {
 	st.LimitValue = &limit
 	return st
@@ -55,7 +104,11 @@ func (st *SelectStatement) Offset(offset int64) *SelectStatement {
 	return st
 }
 
+// Where 空的条件会被忽略，比如 FilterSchema.Parse 没有解析出条件时返回的 nil
 func (st *SelectStatement) Where(query interface{}, args ...interface{}) *SelectStatement {
+	if isEmptyCond(query) {
+		return st
+	}
 	st.Wheres = append(st.Wheres, SqlParam{query: query, args: args})
 	return st
 }
@@ -79,16 +132,39 @@ func (st *SelectStatement) ToSql() (query string, args []interface{}, err error)
 	var sql strings.Builder
 	holdType := st.HolderType
 	sql.WriteString("SELECT ")
+	if st.DistinctValue {
+		sql.WriteString("DISTINCT ")
+	}
 
 	if len(st.Columns) == 0 {
 		return "", nil, fmt.Errorf("select sql lack of column")
 	}
-	sql.WriteString(strings.Join(st.Columns, ","))
+	args, err = appendToSql(st.Columns, ",", &sql, args, holdType)
+	if err != nil {
+		return
+	}
 
 	if st.TableName == "" {
 		return "", nil, fmt.Errorf("select sql lack of TableName")
 	}
-	sql.WriteString(fmt.Sprintf(" FROM %s ", st.TableName))
+	wheres, err := scopeWheres(st.Scope, st.Wheres)
+	if err != nil {
+		return
+	}
+	table, err := shardTable(st.Shards, st.TableName, wheres)
+	if err != nil {
+		return
+	}
+	// 分片用的是没有限定的列名，确定表名之后再限定作用域的列
+	if st.Scope != nil {
+		if wheres, err = scopeWheres(qualifyScope(table, st.Scope), st.Wheres); err != nil {
+			return
+		}
+	}
+	if st.SoftDeleteColumn != "" {
+		wheres = append(groupConds(wheres), Eq{qualifyColumn(table, st.SoftDeleteColumn): nil})
+	}
+	sql.WriteString(fmt.Sprintf(" FROM %s ", table))
 
 	if len(st.Joins) > 0 {
 		args, err = appendToSql(st.Joins, " ", &sql, args, holdType)
@@ -97,9 +173,9 @@ func (st *SelectStatement) ToSql() (query string, args []interface{}, err error)
 		}
 	}
 
-	if len(st.Wheres) > 0 {
+	if len(wheres) > 0 {
 		sql.WriteString(" Where ")
-		args, err = appendToSql(st.Wheres, " AND ", &sql, args, holdType)
+		args, err = appendToSql(wheres, " AND ", &sql, args, holdType)
 		if err != nil {
 			return
 		}
@@ -113,6 +189,14 @@ func (st *SelectStatement) ToSql() (query string, args []interface{}, err error)
 		}
 	}
 
+	if len(st.Windows) > 0 {
+		sql.WriteString(" WINDOW ")
+		args, err = appendToSql(st.Windows, ", ", &sql, args, holdType)
+		if err != nil {
+			return
+		}
+	}
+
 	if len(st.OrderBys) > 0 {
 		sql.WriteString(" ORDER BY ")
 		args, err = appendToSql(st.OrderBys, ", ", &sql, args, holdType)
@@ -128,5 +212,48 @@ func (st *SelectStatement) ToSql() (query string, args []interface{}, err error)
 		sql.WriteString(fmt.Sprintf(" OFFSET %d", *st.OffsetValue))
 	}
 
-	return sql.String(), args, nil
+	return st.HolderType.replace(sql.String()), args, nil
+}
+
+func (st *SelectStatement) Clone() *SelectStatement {
+	clone := *st
+	clone.Columns = cloneConds(st.Columns)
+	clone.Wheres = cloneConds(st.Wheres)
+	clone.OrderBys = cloneConds(st.OrderBys)
+	clone.Joins = cloneConds(st.Joins)
+	clone.GroupBys = cloneConds(st.GroupBys)
+	clone.Windows = cloneConds(st.Windows)
+	clone.LimitValue = cloneInt64(st.LimitValue)
+	clone.OffsetValue = cloneInt64(st.OffsetValue)
+	return &clone
+}
+
+// FanOut 跨分片查询，按所有的物理表生成语句，结果需要调用方自己合并
+func (st *SelectStatement) FanOut() ([]*SelectStatement, error) {
+	name, alias := splitTable(st.TableName)
+	strategy, ok := st.Shards[name]
+	if !ok {
+		return nil, fmt.Errorf("table has no shard strategy, table = %s", name)
+	}
+
+	var list []*SelectStatement
+	for _, table := range strategy.All(name) {
+		clone := st.Clone()
+		clone.TableName = table + alias
+		clone.Shards = nil
+		list = append(list, clone)
+	}
+	return list, nil
+}
+
+// qualifyColumn 用表名或者别名限定列，join 的表有同名列时不会有歧义
+func qualifyColumn(table, column string) string {
+	if strings.Contains(column, ".") {
+		return column
+	}
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return column
+	}
+	return fields[len(fields)-1] + "." + column
 }
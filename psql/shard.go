@@ -0,0 +1,216 @@
+package psql
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShardStrategy 逻辑表到物理表的映射
+type ShardStrategy interface {
+	// Key 分片键的列名
+	Key() string
+	Shard(table string, value interface{}) (string, error)
+	// All 所有的物理表，跨分片查询时使用
+	All(table string) []string
+}
+
+type modShard struct {
+	key   string
+	count int64
+	width int
+}
+
+// ModShard 按 key % count 分表，比如 count 为 64 时是 orders_00 ~ orders_63，count 必须大于 0
+func ModShard(key string, count int64) (ShardStrategy, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("mod shard count must be positive, key = %s, count = %d", key, count)
+	}
+	return &modShard{key: key, count: count, width: len(strconv.FormatInt(count-1, 10))}, nil
+}
+
+func (ms *modShard) Key() string {
+	return ms.key
+}
+
+func (ms *modShard) Shard(table string, value interface{}) (string, error) {
+	var n int64
+	vv := reflect.ValueOf(value)
+	switch vv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = vv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = int64(vv.Uint() % uint64(ms.count))
+	default:
+		return "", fmt.Errorf("mod shard key must be integer, key = %s, value = %#v", ms.key, value)
+	}
+	return ms.name(table, (n%ms.count+ms.count)%ms.count), nil
+}
+
+func (ms *modShard) name(table string, index int64) string {
+	return fmt.Sprintf("%s_%0*d", table, ms.width, index)
+}
+
+func (ms *modShard) All(table string) []string {
+	var tables []string
+	for i := int64(0); i < ms.count; i++ {
+		tables = append(tables, ms.name(table, i))
+	}
+	return tables
+}
+
+type monthShard struct {
+	key  string
+	from time.Time
+	to   time.Time
+}
+
+// MonthShard 按月分表，比如 orders_202601，from 和 to 决定跨分片查询的范围
+func MonthShard(key string, from, to time.Time) ShardStrategy {
+	return &monthShard{key: key, from: from, to: to}
+}
+
+func (ms *monthShard) Key() string {
+	return ms.key
+}
+
+func (ms *monthShard) Shard(table string, value interface{}) (string, error) {
+	t, ok := value.(time.Time)
+	if !ok {
+		return "", fmt.Errorf("month shard key must be time.Time, key = %s, value = %#v", ms.key, value)
+	}
+	return fmt.Sprintf("%s_%s", table, t.Format("200601")), nil
+}
+
+func (ms *monthShard) All(table string) []string {
+	var tables []string
+	month := time.Date(ms.from.Year(), ms.from.Month(), 1, 0, 0, 0, 0, ms.from.Location())
+	for !month.After(ms.to) {
+		tables = append(tables, fmt.Sprintf("%s_%s", table, month.Format("200601")))
+		month = month.AddDate(0, 1, 0)
+	}
+	return tables
+}
+
+// splitTable "orders o" 拆成逻辑表名和别名部分
+func splitTable(table string) (string, string) {
+	name, alias, _ := strings.Cut(table, " ")
+	if alias == "" {
+		return name, ""
+	}
+	return name, " " + alias
+}
+
+// shardTable 从 Eq 条件里找分片键，没有分片规则的表原样返回
+func shardTable(shards map[string]ShardStrategy, table string, wheres []SqlCond) (string, error) {
+	name, alias := splitTable(table)
+	strategy, ok := shards[name]
+	if !ok {
+		return table, nil
+	}
+
+	var values []interface{}
+	for _, where := range wheres {
+		values = append(values, findShardValues(where, strategy.Key())...)
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("sql lack of shard key, table = %s, key = %s", name, strategy.Key())
+	}
+
+	shard, err := sameShard(strategy, name, values)
+	if err != nil {
+		return "", err
+	}
+	return shard + alias, nil
+}
+
+func shardInsertTable(shards map[string]ShardStrategy, table string, columns []string, values [][]interface{}) (string, error) {
+	strategy, ok := shards[table]
+	if !ok {
+		return table, nil
+	}
+
+	index := -1
+	for ci, column := range columns {
+		if column == strategy.Key() {
+			index = ci
+			break
+		}
+	}
+	if index < 0 {
+		return "", fmt.Errorf("insert sql lack of shard key, table = %s, key = %s", table, strategy.Key())
+	}
+
+	var keys []interface{}
+	for _, list := range values {
+		if index >= len(list) {
+			return "", fmt.Errorf("insert value lack of shard key, table = %s, key = %s", table, strategy.Key())
+		}
+		keys = append(keys, list[index])
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("insert sql lack of value, table = %s", table)
+	}
+	return sameShard(strategy, table, keys)
+}
+
+// sameShard 所有的值必须落在同一个分片上
+func sameShard(strategy ShardStrategy, table string, values []interface{}) (string, error) {
+	var shard string
+	for _, value := range values {
+		s, err := strategy.Shard(table, value)
+		if err != nil {
+			return "", err
+		}
+		if shard != "" && s != shard {
+			return "", fmt.Errorf("sql spans multiple shards, table = %s, shards = %s, %s", table, shard, s)
+		}
+		shard = s
+	}
+	return shard, nil
+}
+
+// findShardValues 只看 AND 连接的 Eq 条件，OR 里的条件不能确定分片
+func findShardValues(cond SqlCond, key string) []interface{} {
+	switch c := cond.(type) {
+	case parenCond:
+		return findShardValues(c.cond, key)
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			return findShardValues(q, key)
+		case map[string]interface{}:
+			return findShardValues(Eq(q), key)
+		}
+	case And:
+		var values []interface{}
+		for _, item := range c {
+			values = append(values, findShardValues(item, key)...)
+		}
+		return values
+	case Eq:
+		for column, value := range c {
+			if column != key && !strings.HasSuffix(column, "."+key) {
+				continue
+			}
+			if value == nil {
+				return nil
+			}
+			if _, ok := value.(SqlCond); ok {
+				return nil
+			}
+			if isListType(value) {
+				var values []interface{}
+				vv := reflect.ValueOf(value)
+				for i := 0; i < vv.Len(); i++ {
+					values = append(values, vv.Index(i).Interface())
+				}
+				return values
+			}
+			return []interface{}{value}
+		}
+	}
+	return nil
+}
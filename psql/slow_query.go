@@ -0,0 +1,242 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+const RedactedValue = "***"
+
+type SlowQueryConfig struct {
+	threshold time.Duration
+	sensitive map[string]bool
+	logger    *plog.Logger
+}
+
+type SlowQueryOption func(cfg *SlowQueryConfig)
+
+// SensitiveColumns 这些列对应的参数在日志里会被替换成 ***
+func SensitiveColumns(columns ...string) SlowQueryOption {
+	return func(cfg *SlowQueryConfig) {
+		for _, column := range columns {
+			cfg.sensitive[strings.ToLower(column)] = true
+		}
+	}
+}
+
+// SlowQueryLogger 默认使用 plog 的 root logger
+func SlowQueryLogger(logger *plog.Logger) SlowQueryOption {
+	return func(cfg *SlowQueryConfig) {
+		cfg.logger = logger
+	}
+}
+
+type SlowQueryHook struct {
+	cfg *SlowQueryConfig
+}
+
+func NewSlowQueryHook(threshold time.Duration, options ...SlowQueryOption) *SlowQueryHook {
+	cfg := &SlowQueryConfig{threshold: threshold, sensitive: make(map[string]bool)}
+	for _, option := range options {
+		option(cfg)
+	}
+	return &SlowQueryHook{cfg: cfg}
+}
+
+func (h *SlowQueryHook) Before(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *SlowQueryHook) After(ctx context.Context, event *QueryEvent) {
+	if event.Duration < h.cfg.threshold {
+		return
+	}
+
+	args := redactArgs(event.Statement, event.Args, h.cfg.sensitive)
+	format := "[psql] slow query, duration = %s, rows = %d, sql = %s, args = %v"
+	if h.cfg.logger != nil {
+		h.cfg.logger.WithError(event.Err).Warnf(format, event.Duration, event.RowsAffected, event.Query, args)
+		return
+	}
+	plog.WithError(event.Err).Warnf(format, event.Duration, event.RowsAffected, event.Query, args)
+}
+
+// redactArgs 按语句的结构找到每个参数对应的列，敏感列和找不到对应列的参数都替换成 ***，
+// 比如 Where("id = ?", 1) 这种原生条件没办法判断列，直接执行的 sql 没有语句结构，参数全部替换
+func redactArgs(st SqlStatement, args []interface{}, sensitive map[string]bool) []interface{} {
+	if len(sensitive) == 0 || len(args) == 0 {
+		return args
+	}
+
+	columns, err := argColumns(st)
+	attributed := err == nil && len(columns) == len(args)
+	redacted := make([]interface{}, len(args))
+	for index, arg := range args {
+		redacted[index] = RedactedValue
+		if !attributed || columns[index] == "" {
+			continue
+		}
+		column := strings.ToLower(columns[index])
+		if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+			column = column[dot+1:]
+		}
+		if !sensitive[column] {
+			redacted[index] = arg
+		}
+	}
+	return redacted
+}
+
+// argColumns 按 ToSql 输出参数的顺序返回每个参数对应的列，对应不上的为空字符串
+func argColumns(st SqlStatement) ([]string, error) {
+	switch s := st.(type) {
+	case *SelectStatement:
+		return s.argColumns()
+	case *InsertStatement:
+		return s.argColumns()
+	case *UpdateStatement:
+		return s.argColumns()
+	case *DeleteStatement:
+		if s.SoftDeleteColumn != "" {
+			return s.softUpdate().argColumns()
+		}
+		wheres, err := scopeWheres(s.Scope, s.Wheres)
+		if err != nil {
+			return nil, err
+		}
+		return condsArgColumns(wheres, s.HolderType)
+	}
+	return nil, fmt.Errorf("statement can not be attributed, statement = %T", st)
+}
+
+func (st *SelectStatement) argColumns() ([]string, error) {
+	columns, err := condsArgColumns(st.Columns, st.HolderType)
+	if err != nil {
+		return nil, err
+	}
+	joins, err := condsArgColumns(st.Joins, st.HolderType)
+	if err != nil {
+		return nil, err
+	}
+	// 软删除的条件没有参数，不影响顺序
+	wheres, err := scopeWheres(st.Scope, st.Wheres)
+	if err != nil {
+		return nil, err
+	}
+	whereColumns, err := condsArgColumns(wheres, st.HolderType)
+	if err != nil {
+		return nil, err
+	}
+	columns = append(append(columns, joins...), whereColumns...)
+	for _, conds := range [][]SqlCond{st.GroupBys, st.Windows, st.OrderBys} {
+		list, err := condsArgColumns(conds, st.HolderType)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, list...)
+	}
+	return columns, nil
+}
+
+func (it *InsertStatement) argColumns() ([]string, error) {
+	columns, values, err := it.scoped()
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, row := range values {
+		for index := range row {
+			column := ""
+			if index < len(columns) {
+				column = columns[index]
+			}
+			list = append(list, column)
+		}
+	}
+	return list, nil
+}
+
+func (t *UpdateStatement) argColumns() ([]string, error) {
+	sets, wheres := t.versioned()
+	wheres, err := scopeWheres(t.Scope, wheres)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, set := range sets {
+		// Set("password", Expr("SHA2(?,256)", secret)) 表达式里的参数也属于这一列
+		_, args, err := valueToSql(set.Value, t.HolderType)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, repeatColumn(set.Column, len(args))...)
+	}
+	whereColumns, err := condsArgColumns(wheres, t.HolderType)
+	if err != nil {
+		return nil, err
+	}
+	return append(columns, whereColumns...), nil
+}
+
+func condsArgColumns(conds []SqlCond, pt PlaceHolderType) ([]string, error) {
+	var columns []string
+	for _, cond := range conds {
+		list, err := condArgColumns(cond, pt)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, list...)
+	}
+	return columns, nil
+}
+
+func condArgColumns(cond SqlCond, pt PlaceHolderType) ([]string, error) {
+	switch c := cond.(type) {
+	case parenCond:
+		return condArgColumns(c.cond, pt)
+	case SqlParam:
+		switch q := c.query.(type) {
+		case SqlCond:
+			return condArgColumns(q, pt)
+		case map[string]interface{}:
+			return condArgColumns(Eq(q), pt)
+		}
+	case And:
+		return condsArgColumns(c, pt)
+	case Or:
+		return condsArgColumns(c, pt)
+	case likeCond:
+		return []string{c.column}, nil
+	}
+
+	if data, ok := condExpr(cond); ok {
+		var columns []string
+		for _, key := range sortedKeys(data) {
+			_, args, err := exprToSql(expr{key: data[key]}, eq, pt)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, repeatColumn(key, len(args))...)
+		}
+		return columns, nil
+	}
+
+	_, args, err := cond.ToWhere(pt)
+	if err != nil {
+		return nil, err
+	}
+	return make([]string, len(args)), nil
+}
+
+func repeatColumn(column string, n int) []string {
+	columns := make([]string, n)
+	for index := range columns {
+		columns[index] = column
+	}
+	return columns
+}
@@ -3,28 +3,69 @@ package psql
 import (
 	"fmt"
 	"io"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 type PlaceHolderType int
 
 const (
 	Question PlaceHolderType = iota
+	// Dollar postgres 的 $1、$2 形式，构造时和 Question 一样用 ?，ToSql 最后统一编号
+	Dollar
 )
 
 func (pt PlaceHolderType) Mark() string {
 	switch pt {
-	case Question:
+	case Question, Dollar:
 		return "?"
 	}
 	return ""
 }
 
+// replace Dollar 时把 ? 按顺序换成 $n，单引号里的不替换，?? 输出一个 ?
+func (pt PlaceHolderType) replace(query string) string {
+	if pt != Dollar || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var sql strings.Builder
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			quoted = !quoted
+		} else if c == '?' && !quoted {
+			if i+1 < len(query) && query[i+1] == '?' {
+				sql.WriteByte('?')
+				i++
+				continue
+			}
+			n++
+			sql.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sql.WriteByte(c)
+	}
+	return sql.String()
+}
+
 type SqlCond interface {
 	ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error)
 }
 
 type SqlBuilder struct {
 	HolderType PlaceHolderType
+	Dialect    Dialect
+	// 不为空时开启软删除，Delete 变成更新这一列，Select 只查这一列为 NULL 的数据
+	SoftDeleteColumn string
+	VersionColumn    string
+	// 不为空时 Select、Update、Delete 自动加上这个条件，Insert 自动加上这些列
+	Scope Eq
+	// 逻辑表名到分片规则，语句里的表名会根据分片键改写成物理表名
+	Shards map[string]ShardStrategy
 }
 
 type SqlStatement interface {
@@ -35,20 +76,94 @@ func NewSqlBuilder(holderType PlaceHolderType) SqlBuilder {
 	return SqlBuilder{HolderType: holderType}
 }
 
+func (s SqlBuilder) WithDialect(dialect Dialect) SqlBuilder {
+	s.Dialect = dialect
+	return s
+}
+
+func (s SqlBuilder) WithSoftDelete(column string) SqlBuilder {
+	s.SoftDeleteColumn = column
+	return s
+}
+
+func (s SqlBuilder) Scoped(scope Eq) SqlBuilder {
+	s.Scope = scope
+	return s
+}
+
+func (s SqlBuilder) WithShard(table string, strategy ShardStrategy) SqlBuilder {
+	shards := make(map[string]ShardStrategy, len(s.Shards)+1)
+	for name, st := range s.Shards {
+		shards[name] = st
+	}
+	shards[table] = strategy
+	s.Shards = shards
+	return s
+}
+
+func (s SqlBuilder) WithVersionColumn(column string) SqlBuilder {
+	s.VersionColumn = column
+	return s
+}
+
 func (s SqlBuilder) Select(columns ...string) *SelectStatement {
-	return NewSelect(s.HolderType).Column(columns...)
+	st := NewSelect(s.HolderType).Column(columns...)
+	st.SoftDeleteColumn = s.SoftDeleteColumn
+	st.Scope = s.Scope
+	st.Shards = s.Shards
+	return st
 }
 
 func (s SqlBuilder) Insert(table string) *InsertStatement {
-	return NewInsert(s.HolderType).Table(table)
+	st := NewInsert(s.HolderType).Table(table)
+	st.Scope = s.Scope
+	st.Shards = s.Shards
+	return st
 }
 
 func (s SqlBuilder) Delete(table string) *DeleteStatement {
-	return NewDelete(s.HolderType).Table(table)
+	st := NewDelete(s.HolderType).Table(table)
+	st.SoftDeleteColumn = s.SoftDeleteColumn
+	st.Scope = s.Scope
+	st.Shards = s.Shards
+	return st
 }
 
 func (s SqlBuilder) Update(table string) *UpdateStatement {
-	return NewUpdate(s.HolderType).Table(table)
+	st := NewUpdate(s.HolderType).Table(table)
+	st.Scope = s.Scope
+	st.Shards = s.Shards
+	return st
+}
+
+// UpdateVersioned 乐观锁更新，会加上 version = version + 1 和 WHERE version = ?，
+// 通过 Exec 执行时影响行数为 0 会返回 *ConflictError
+func (s SqlBuilder) UpdateVersioned(table string, version interface{}) *UpdateStatement {
+	column := s.VersionColumn
+	if column == "" {
+		column = "version"
+	}
+	return s.Update(table).Versioned(column, version)
+}
+
+func (s SqlBuilder) CreateTable(table string) *CreateTableStatement {
+	return NewCreateTable(s.Dialect).Table(table)
+}
+
+func (s SqlBuilder) AlterTable(table string) *AlterTableStatement {
+	return NewAlterTable(s.Dialect).Table(table)
+}
+
+func (s SqlBuilder) DropTable(table string) *DropTableStatement {
+	return NewDropTable().Table(table)
+}
+
+func (s SqlBuilder) CreateIndex(name, table string, columns ...string) *CreateIndexStatement {
+	return NewCreateIndex(s.Dialect).Name(name).On(table, columns...)
+}
+
+func (s SqlBuilder) DropIndex(name string) *DropIndexStatement {
+	return NewDropIndex(s.Dialect).Name(name)
 }
 
 func Select(columns ...string) *SelectStatement {
@@ -67,6 +182,26 @@ func Update(table string) *UpdateStatement {
 	return NewUpdate(Question).Table(table)
 }
 
+func CreateTable(table string) *CreateTableStatement {
+	return NewCreateTable(MySQL).Table(table)
+}
+
+func AlterTable(table string) *AlterTableStatement {
+	return NewAlterTable(MySQL).Table(table)
+}
+
+func DropTable(table string) *DropTableStatement {
+	return NewDropTable().Table(table)
+}
+
+func CreateIndex(name, table string, columns ...string) *CreateIndexStatement {
+	return NewCreateIndex(MySQL).Name(name).On(table, columns...)
+}
+
+func DropIndex(name string) *DropIndexStatement {
+	return NewDropIndex(MySQL).Name(name)
+}
+
 type SqlParam struct {
 	query interface{}
 	args  []interface{}
@@ -80,14 +215,31 @@ func (sp SqlParam) ToWhere(pt PlaceHolderType) (query string, args []interface{}
 
 	switch qt := sp.query.(type) {
 	case string:
-		return qt, args, nil
+		return qt, sp.args, nil
 	case map[string]interface{}:
 		return Eq(qt).ToWhere(pt)
 	default:
-		return query, args, fmt.Errorf("query has wrong type. query = %#v", query)
+		return query, args, fmt.Errorf("query has wrong type. query = %#v", sp.query)
 	}
 }
 
+// isEmptyCond nil、空的 And/Or 和空的 Eq 等条件
+func isEmptyCond(query interface{}) bool {
+	if query == nil {
+		return true
+	}
+	switch q := query.(type) {
+	case And:
+		return len(q) == 0
+	case Or:
+		return len(q) == 0
+	case string:
+		return strings.TrimSpace(q) == ""
+	}
+	value := reflect.ValueOf(query)
+	return value.Kind() == reflect.Map && value.Len() == 0
+}
+
 func appendToSql(transforms []SqlCond, connect string, writer io.Writer, args []interface{}, holderType PlaceHolderType) ([]interface{}, error) {
 	for index, tran := range transforms {
 		if index > 0 {
@@ -109,3 +261,58 @@ func appendToSql(transforms []SqlCond, connect string, writer io.Writer, args []
 
 	return args, nil
 }
+
+// cloneConds 复制切片，避免从同一个语句派生出来的语句 append 时互相覆盖
+func cloneConds(conds []SqlCond) []SqlCond {
+	if conds == nil {
+		return nil
+	}
+	clone := make([]SqlCond, 0, len(conds))
+	for _, cond := range conds {
+		clone = append(clone, cloneCond(cond))
+	}
+	return clone
+}
+
+// cloneCond CaseExpr、WindowSpec 这类通过指针修改的表达式需要深拷贝，其他条件是值类型直接复用
+func cloneCond(cond SqlCond) SqlCond {
+	switch c := cond.(type) {
+	case *CaseExpr:
+		return c.clone()
+	case *WindowSpec:
+		return c.clone()
+	case WindowExpr:
+		c.Func = cloneCond(c.Func)
+		c.Spec = c.Spec.clone()
+		return c
+	case namedWindow:
+		c.spec = c.spec.clone()
+		return c
+	case AliasExpr:
+		c.Expr = cloneCond(c.Expr)
+		return c
+	case SqlParam:
+		c.query = cloneValue(c.query)
+		return c
+	case And:
+		return And(cloneConds(c))
+	case Or:
+		return Or(cloneConds(c))
+	}
+	return cond
+}
+
+func cloneValue(value interface{}) interface{} {
+	if cond, ok := value.(SqlCond); ok {
+		return cloneCond(cond)
+	}
+	return value
+}
+
+func cloneInt64(value *int64) *int64 {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
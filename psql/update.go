@@ -11,14 +11,20 @@ type SetParam struct {
 	Value  interface{}
 }
 type UpdateStatement struct {
-	HolderType PlaceHolderType
-	TableName  string
-	Sets       []SetParam
-	Wheres     []SqlCond
+	HolderType    PlaceHolderType
+	TableName     string
+	Sets          []SetParam
+	Wheres        []SqlCond
+	VersionColumn string
+	VersionValue  interface{}
+	Scope         Eq
+	Shards        map[string]ShardStrategy
+	// 传入过空的条件，没有其他条件时 ToSql 报错，不会变成更新整张表
+	emptyWhere bool
 }
 
 func NewUpdate(holderType PlaceHolderType) *UpdateStatement {
-	return &UpdateStatement{HolderType: Question}
+	return &UpdateStatement{HolderType: holderType}
 }
 
 func (t *UpdateStatement) Table(table string) *UpdateStatement {
@@ -44,49 +50,108 @@ func (t *UpdateStatement) SetMap(data map[string]interface{}) *UpdateStatement {
 	return t
 }
 
+// Where 空的条件会被忽略，只有空的条件时 ToSql 返回错误
 func (t *UpdateStatement) Where(query interface{}, args ...interface{}) *UpdateStatement {
+	if isEmptyCond(query) {
+		t.emptyWhere = true
+		return t
+	}
 	t.Wheres = append(t.Wheres, SqlParam{query: query, args: args})
 	return t
 }
 
+func (t *UpdateStatement) Unscoped() *UpdateStatement {
+	t.Scope = nil
+	return t
+}
+
+// Versioned 乐观锁，ToSql 时加上 column = column + 1 和 WHERE column = version
+func (t *UpdateStatement) Versioned(column string, version interface{}) *UpdateStatement {
+	t.VersionColumn = column
+	t.VersionValue = version
+	return t
+}
+
 func (t *UpdateStatement) ToSql() (query string, args []interface{}, err error) {
+	if t.emptyWhere && len(t.Wheres) == 0 {
+		return "", nil, fmt.Errorf("update sql where condition is empty")
+	}
+	sets, wheres := t.versioned()
+	for _, set := range sets {
+		if err = checkScopeValue(t.Scope, set.Column, set.Value); err != nil {
+			return
+		}
+	}
+	wheres, err = scopeWheres(t.Scope, wheres)
+	if err != nil {
+		return
+	}
+	table, err := shardTable(t.Shards, t.TableName, wheres)
+	if err != nil {
+		return
+	}
+
 	var sql strings.Builder
-	_, err = sql.WriteString(fmt.Sprintf("UPDATE %s ", t.TableName))
+	_, err = sql.WriteString(fmt.Sprintf("UPDATE %s ", table))
 	if err != nil {
 		return
 	}
 
-	if len(t.Sets) > 0 {
+	if len(sets) > 0 {
 		_, err = sql.WriteString("SET ")
 		if err != nil {
 			return
 		}
-		for index, set := range t.Sets {
+		for index, set := range sets {
 			if index > 0 {
 				_, err = sql.WriteString(",")
 				if err != nil {
 					return
 				}
 			}
-			_, err = sql.WriteString(fmt.Sprintf("%s=%s", set.Column, t.HolderType.Mark()))
+			vq, vs, err := valueToSql(set.Value, t.HolderType)
+			if err != nil {
+				return "", nil, err
+			}
+			_, err = sql.WriteString(fmt.Sprintf("%s=%s", set.Column, vq))
 			if err != nil {
-				return
+				return "", nil, err
 			}
-			args = append(args, set.Value)
+			args = append(args, vs...)
 		}
 	}
 
-	if len(t.Wheres) > 0 {
+	if len(wheres) > 0 {
 		_, err = sql.WriteString(" WHERE ")
 		if err != nil {
 			return
 		}
 
-		args, err = appendToSql(t.Wheres, " AND ", &sql, args, t.HolderType)
+		args, err = appendToSql(wheres, " AND ", &sql, args, t.HolderType)
 		if err != nil {
 			return
 		}
 	}
 
-	return sql.String(), args, nil
+	return t.HolderType.replace(sql.String()), args, nil
+}
+
+// versioned 加上乐观锁的 SET 和 WHERE
+func (t *UpdateStatement) versioned() ([]SetParam, []SqlCond) {
+	sets, wheres := t.Sets, t.Wheres
+	if t.VersionColumn != "" {
+		sets = append(append([]SetParam(nil), sets...), SetParam{Column: t.VersionColumn, Value: Expr(t.VersionColumn + " + 1")})
+		wheres = append(groupConds(wheres), Eq{t.VersionColumn: t.VersionValue})
+	}
+	return sets, wheres
+}
+
+func (t *UpdateStatement) Clone() *UpdateStatement {
+	clone := *t
+	clone.Sets = nil
+	for _, set := range t.Sets {
+		clone.Sets = append(clone.Sets, SetParam{Column: set.Column, Value: cloneValue(set.Value)})
+	}
+	clone.Wheres = cloneConds(t.Wheres)
+	return &clone
 }
@@ -0,0 +1,184 @@
+package psql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FrameBound string
+
+const (
+	UnboundedPreceding FrameBound = "UNBOUNDED PRECEDING"
+	CurrentRow         FrameBound = "CURRENT ROW"
+	UnboundedFollowing FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+func Preceding(n int64) FrameBound {
+	return FrameBound(fmt.Sprintf("%d PRECEDING", n))
+}
+
+func Following(n int64) FrameBound {
+	return FrameBound(fmt.Sprintf("%d FOLLOWING", n))
+}
+
+type WindowSpec struct {
+	Partitions []SqlCond
+	OrderBys   []SqlCond
+	Frame      string
+}
+
+func Window() *WindowSpec {
+	return &WindowSpec{}
+}
+
+func (ws *WindowSpec) PartitionBy(columns ...string) *WindowSpec {
+	for _, column := range columns {
+		ws.Partitions = append(ws.Partitions, SqlParam{query: column})
+	}
+	return ws
+}
+
+func (ws *WindowSpec) OrderBy(orderBys ...string) *WindowSpec {
+	for _, orderBy := range orderBys {
+		ws.OrderBys = append(ws.OrderBys, SqlParam{query: orderBy})
+	}
+	return ws
+}
+
+func (ws *WindowSpec) OrderByExpr(orderBys ...SqlCond) *WindowSpec {
+	ws.OrderBys = append(ws.OrderBys, orderBys...)
+	return ws
+}
+
+func (ws *WindowSpec) RowsBetween(start, end FrameBound) *WindowSpec {
+	ws.Frame = fmt.Sprintf("ROWS BETWEEN %s AND %s", start, end)
+	return ws
+}
+
+func (ws *WindowSpec) RangeBetween(start, end FrameBound) *WindowSpec {
+	ws.Frame = fmt.Sprintf("RANGE BETWEEN %s AND %s", start, end)
+	return ws
+}
+
+func (ws *WindowSpec) clone() *WindowSpec {
+	if ws == nil {
+		return nil
+	}
+	clone := *ws
+	clone.Partitions = cloneConds(ws.Partitions)
+	clone.OrderBys = cloneConds(ws.OrderBys)
+	return &clone
+}
+
+// ToWhere 输出括号内的部分，比如 PARTITION BY a ORDER BY b ROWS BETWEEN ...
+func (ws *WindowSpec) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	var sql strings.Builder
+	if len(ws.Partitions) > 0 {
+		sql.WriteString("PARTITION BY ")
+		args, err = appendToSql(ws.Partitions, ", ", &sql, args, pt)
+		if err != nil {
+			return
+		}
+	}
+
+	if len(ws.OrderBys) > 0 {
+		if sql.Len() > 0 {
+			sql.WriteString(" ")
+		}
+		sql.WriteString("ORDER BY ")
+		args, err = appendToSql(ws.OrderBys, ", ", &sql, args, pt)
+		if err != nil {
+			return
+		}
+	}
+
+	if ws.Frame != "" {
+		if sql.Len() > 0 {
+			sql.WriteString(" ")
+		}
+		sql.WriteString(ws.Frame)
+	}
+
+	return sql.String(), args, nil
+}
+
+type WindowExpr struct {
+	Func       SqlCond
+	Spec       *WindowSpec
+	WindowName string
+}
+
+func (we WindowExpr) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	fq, fs, err := we.Func.ToWhere(pt)
+	if err != nil {
+		return "", nil, err
+	}
+	if we.WindowName != "" {
+		return fmt.Sprintf("%s OVER %s", fq, we.WindowName), fs, nil
+	}
+	if we.Spec == nil {
+		return "", nil, fmt.Errorf("window expression lack of window")
+	}
+
+	sq, ss, err := we.Spec.ToWhere(pt)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s OVER (%s)", fq, sq), append(fs, ss...), nil
+}
+
+func (we WindowExpr) As(alias string) AliasExpr {
+	return As(we, alias)
+}
+
+func (e SqlExpr) Over(spec *WindowSpec) WindowExpr {
+	return WindowExpr{Func: e, Spec: spec}
+}
+
+// OverWindow 引用 SELECT 中 WINDOW 子句定义的窗口
+func (e SqlExpr) OverWindow(name string) WindowExpr {
+	return WindowExpr{Func: e, WindowName: name}
+}
+
+type namedWindow struct {
+	name string
+	spec *WindowSpec
+}
+
+func (nw namedWindow) ToWhere(pt PlaceHolderType) (query string, args []interface{}, err error) {
+	if nw.name == "" || nw.spec == nil {
+		return "", nil, fmt.Errorf("named window lack of name or spec, name = %s", nw.name)
+	}
+	sq, ss, err := nw.spec.ToWhere(pt)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s AS (%s)", nw.name, sq), ss, nil
+}
+
+func RowNumber() SqlExpr {
+	return Expr("ROW_NUMBER()")
+}
+
+func Rank() SqlExpr {
+	return Expr("RANK()")
+}
+
+func DenseRank() SqlExpr {
+	return Expr("DENSE_RANK()")
+}
+
+func Lag(column string, offset int64, defaultValue ...interface{}) SqlExpr {
+	return offsetFunc("LAG", column, offset, defaultValue)
+}
+
+func Lead(column string, offset int64, defaultValue ...interface{}) SqlExpr {
+	return offsetFunc("LEAD", column, offset, defaultValue)
+}
+
+func offsetFunc(fn, column string, offset int64, defaultValue []interface{}) SqlExpr {
+	if len(defaultValue) > 0 {
+		return Expr(fmt.Sprintf("%s(%s, %d, ?)", fn, column, offset), defaultValue[0])
+	}
+	return Expr(fmt.Sprintf("%s(%s, %d)", fn, column, offset))
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yongpi/putil/psql"
+)
+
+func TestCondAST(t *testing.T) {
+	schema := psql.FilterSchema{
+		"status": {Column: "status", Type: psql.StringField, Ops: []psql.FilterOp{psql.OpEq, psql.OpNotEq}},
+		"age":    {Column: "age", Type: psql.IntField, Ops: []psql.FilterOp{psql.OpGte, psql.OpLt}},
+		"name":   {Column: "name", Type: psql.StringField, Ops: []psql.FilterOp{psql.OpLike}},
+	}
+
+	cond := psql.And{
+		psql.Eq{"status": []string{"a", "b"}},
+		psql.Or{psql.Gte{"age": 18}, psql.Like{"name": "foo%"}},
+		psql.NotEq{"status": nil},
+	}
+	data, err := psql.MarshalCond(cond, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exData := `{"version":1,"cond":{"op":"and","conds":[{"op":"eq","values":{"status":["a","b"]}},` +
+		`{"op":"or","conds":[{"op":"gte","values":{"age":18}},{"op":"like","values":{"name":"foo%"}}]},` +
+		`{"op":"ne","values":{"status":null}}]}}`
+	if string(data) != exData {
+		t.Errorf("data not expected json, data = %s", data)
+	}
+
+	rebuilt, err := psql.UnmarshalCond(data, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args, err := rebuilt.ToWhere(psql.Question)
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery, exArgs, _ := cond.ToWhere(psql.Question)
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	if len(args) != len(exArgs) || args[2] != int64(18) {
+		t.Errorf("args not expected value, args = %#v", args)
+	}
+
+	bad := map[string]error{
+		`{"version":1,"cond":{"op":"eq","values":{"password":"x"}}}`:       psql.ErrFilterUnknownField,
+		`{"version":1,"cond":{"op":"eq","values":{"age":18}}}`:             psql.ErrFilterOpNotAllowed,
+		`{"version":1,"cond":{"op":"gte","values":{"age":"eighteen"}}}`:    psql.ErrFilterInvalidValue,
+		`{"version":1,"cond":{"op":"gte","values":{"age":[1,2]}}}`:         psql.ErrFilterInvalidValue,
+		`{"version":1,"cond":{"op":"raw","values":{"status":"1 OR 1=1"}}}`: psql.ErrFilterOpNotAllowed,
+	}
+	for data, exErr := range bad {
+		if _, err = psql.UnmarshalCond([]byte(data), schema); !errors.Is(err, exErr) {
+			t.Errorf("error not expected, data = %s, err = %v", data, err)
+		}
+	}
+	if _, err = psql.UnmarshalCond([]byte(`{"version":2,"cond":{"op":"eq","values":{"status":"a"}}}`), schema); err == nil {
+		t.Error("unknown version should return error")
+	}
+	if _, err = psql.MarshalCond(psql.Or{psql.Eq{"status": 1}, psql.Expr("b = ?", 1)}, schema); err == nil {
+		t.Error("raw condition should return error")
+	}
+	if _, err = psql.MarshalCond(psql.Eq{"password": "x"}, schema); !errors.Is(err, psql.ErrFilterUnknownField) {
+		t.Errorf("unknown column error expected, err = %v", err)
+	}
+
+	// FilterSchema 解析出来的 like 带 ESCAPE，往返之后不变
+	filtered, err := schema.ParseMap(map[string]string{"name_like": "a_b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = psql.MarshalCond(filtered, schema); err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt, err = psql.UnmarshalCond(data, schema); err != nil {
+		t.Fatal(err)
+	}
+	query, args, _ = rebuilt.ToWhere(psql.Question)
+	exQuery, _, _ = filtered.ToWhere(psql.Question)
+	if query != exQuery || len(args) != 1 || args[0] != "%a!_b%" {
+		t.Errorf("escaped like not expected, query = %s, args = %#v", query, args)
+	}
+}
+
+func TestSelectAST(t *testing.T) {
+	schemas := map[string]psql.FilterSchema{
+		"users": {
+			"id":     {Column: "id", Type: psql.IntField},
+			"name":   {Column: "name", Type: psql.StringField},
+			"status": {Column: "status", Type: psql.IntField, Ops: []psql.FilterOp{psql.OpEq, psql.OpGt}},
+			// 字段名和列名不一样
+			"createdAt": {Column: "created_at", Type: psql.IntField, Ops: []psql.FilterOp{psql.OpGte}},
+		},
+	}
+
+	st := psql.Select("id", "name", "created_at").
+		Distinct().
+		From("users").
+		Where(psql.Eq{"status": 1}).
+		Where(psql.Gt{"status": 0}).
+		Where(psql.Gte{"created_at": 100}).
+		OrderByTerms(psql.Desc("created_at").NullsLast()).
+		Limit(10).
+		Offset(20)
+	data, err := psql.MarshalSelect(st, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exData := `{"version":1,"select":{"table":"users","distinct":true,"columns":["id","name","createdAt"],` +
+		`"where":{"op":"and","conds":[{"op":"eq","values":{"status":1}},{"op":"gt","values":{"status":0}},{"op":"gte","values":{"createdAt":100}}]},` +
+		`"order_by":[{"field":"createdAt","desc":true,"nulls":"last"}],"limit":10,"offset":20}}`
+	if string(data) != exData {
+		t.Errorf("data not expected json, data = %s", data)
+	}
+
+	rebuilt, err := psql.UnmarshalSelect(data, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args, err := rebuilt.ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery := "SELECT DISTINCT id,name,created_at FROM users  Where (status = ? AND status > ? AND created_at >= ?) ORDER BY created_at IS NULL ASC, created_at DESC LIMIT 10 OFFSET 20"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	if len(args) != 3 || args[0] != int64(1) || args[1] != int64(0) || args[2] != int64(100) {
+		t.Errorf("args not expected value, args = %#v", args)
+	}
+
+	// builder 的作用域和软删除对还原出来的语句生效
+	builder := psql.NewSqlBuilder(psql.Question).Scoped(psql.Eq{"tenant_id": 7}).WithSoftDelete("deleted_at")
+	scoped, err := builder.UnmarshalSelect(data, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query, args, err = scoped.ToSql(); err != nil {
+		t.Error(err)
+	}
+	exQuery = "SELECT DISTINCT id,name,created_at FROM users  Where (status = ? AND status > ? AND created_at >= ?) AND users.tenant_id = ? AND users.deleted_at IS NULL ORDER BY created_at IS NULL ASC, created_at DESC LIMIT 10 OFFSET 20"
+	if query != exQuery || len(args) != 4 || args[3] != 7 {
+		t.Errorf("scoped query not expected sql, query = %s, args = %#v", query, args)
+	}
+
+	if _, err = psql.MarshalSelect(psql.Select("password").From("users"), schemas); !errors.Is(err, psql.ErrFilterUnknownField) {
+		t.Errorf("unknown column error expected, err = %v", err)
+	}
+
+	if _, err = psql.UnmarshalSelect([]byte(`{"version":1,"select":{"table":"orders","columns":["id"]}}`), schemas); !errors.Is(err, psql.ErrFilterUnknownField) {
+		t.Errorf("unknown table error expected, err = %v", err)
+	}
+	if _, err = psql.UnmarshalSelect([]byte(`{"version":1,"select":{"table":"users","columns":["password"]}}`), schemas); !errors.Is(err, psql.ErrFilterUnknownField) {
+		t.Errorf("unknown column error expected, err = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+// gateWriter 在 gate 关闭之前阻塞写入，用来模拟慢磁盘
+type gateWriter struct {
+	sync.Mutex
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	buffer  bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gate
+	w.Lock()
+	defer w.Unlock()
+	return w.buffer.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.Lock()
+	defer w.Unlock()
+	return w.buffer.String()
+}
+
+func newAsyncLogger(out *plog.AsyncWriter) *plog.Logger {
+	logger := plog.NewLogger(plog.DEBUG)
+	logger.Out = out
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+	logger.AutoCaller = false
+	return logger
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := plog.NewAsyncWriter(&buffer)
+	logger := newAsyncLogger(writer)
+
+	for i := 0; i < 100; i++ {
+		logger.Infof("line %d", i)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buffer.String(), "\n") != 100 || writer.Dropped() != 0 {
+		t.Errorf("flush should drain buffer, lines = %d, dropped = %d", strings.Count(buffer.String(), "\n"), writer.Dropped())
+	}
+	_ = writer.Close()
+}
+
+func TestAsyncWriterDropNewest(t *testing.T) {
+	out := newGateWriter()
+	writer := plog.NewAsyncWriter(out, plog.BufferSize(2), plog.Overflow(plog.OverflowDropNewest))
+	logger := newAsyncLogger(writer)
+
+	// 第一条被后台协程取走并阻塞在 out 上
+	logger.Info("0")
+	<-out.started
+	for _, msg := range []string{"1", "2", "3", "4"} {
+		logger.Info(msg)
+	}
+	close(out.gate)
+	_ = writer.Close()
+
+	if writer.Dropped() != 2 {
+		t.Errorf("dropped not expected, dropped = %d", writer.Dropped())
+	}
+	if strings.Contains(out.String(), "msg=3") || !strings.Contains(out.String(), "msg=2") {
+		t.Errorf("newest should be dropped, output = %s", out.String())
+	}
+}
+
+func TestAsyncWriterDropLowLevel(t *testing.T) {
+	out := newGateWriter()
+	writer := plog.NewAsyncWriter(out, plog.BufferSize(2), plog.Overflow(plog.OverflowDropLowLevel))
+	entry := newAsyncLogger(writer).WithTime(logTime)
+
+	entry.Info("first")
+	<-out.started
+	entry.Debug("debug")
+	entry.Info("info")
+	entry.Error("error")
+	entry.Debug("debug again")
+	close(out.gate)
+	_ = writer.Close()
+
+	exOut := "level=info ts=2020-01-02T03:04:05Z msg=first\nlevel=info ts=2020-01-02T03:04:05Z msg=info\nlevel=error ts=2020-01-02T03:04:05Z msg=error\n"
+	if out.String() != exOut || writer.Dropped() != 2 {
+		t.Errorf("low level should be dropped first, output = %s, dropped = %d", out.String(), writer.Dropped())
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := newGateWriter()
+	writer := plog.NewAsyncWriter(out, plog.BufferSize(1))
+	logger := newAsyncLogger(writer)
+
+	logger.Info("0")
+	<-out.started
+	logger.Info("1")
+
+	done := make(chan struct{})
+	go func() {
+		logger.Info("2")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("write should block when buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(out.gate)
+	<-done
+	_ = writer.Close()
+	if strings.Count(out.String(), "\n") != 3 || writer.Dropped() != 0 {
+		t.Errorf("block should not drop, output = %s", out.String())
+	}
+}
+
+func TestAsyncWriterWriteAfterClose(t *testing.T) {
+	out := newGateWriter()
+	writer := plog.NewAsyncWriter(out)
+	entry := newAsyncLogger(writer).WithTime(logTime)
+
+	entry.Info("0")
+	<-out.started
+	entry.Info("1")
+	closed := make(chan struct{})
+	go func() {
+		_ = writer.Close()
+		close(closed)
+	}()
+	// 等 Close 标记关闭，此时最后一批还阻塞在 out 上
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		entry.Info("after")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("write after close should wait for the final batch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(out.gate)
+	<-done
+	<-closed
+	exOut := "level=info ts=2020-01-02T03:04:05Z msg=0\nlevel=info ts=2020-01-02T03:04:05Z msg=1\nlevel=info ts=2020-01-02T03:04:05Z msg=after\n"
+	if out.String() != exOut {
+		t.Errorf("write after close should be serialized, output = %s", out.String())
+	}
+}
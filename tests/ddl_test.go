@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/yongpi/putil/psql"
+)
+
+func TestCreateTable(t *testing.T) {
+	newStatement := func(dialect psql.Dialect) *psql.CreateTableStatement {
+		return psql.NewSqlBuilder(psql.Question).WithDialect(dialect).
+			CreateTable("orders").
+			IfNotExists().
+			Column(
+				psql.NewColumnDef("id", psql.BigIntType).AutoIncrement(),
+				psql.NewColumnDef("user_id", psql.BigIntType).NotNull(),
+				psql.NewColumnDef("title", psql.VarcharType(64)).NotNull().Default("it's"),
+				psql.NewColumnDef("paid", psql.BoolType).Default(false),
+				psql.NewColumnDef("created_at", psql.TimestampType).Default(psql.Expr("CURRENT_TIMESTAMP")),
+			).
+			PrimaryKey("id").
+			Unique("uk_user_title", "user_id", "title").
+			ForeignKey(psql.ForeignKey("user_id").References("users", "id").OnDelete("CASCADE"))
+	}
+
+	cases := map[psql.Dialect]string{
+		psql.MySQL: "CREATE TABLE IF NOT EXISTS orders (id BIGINT NOT NULL AUTO_INCREMENT, user_id BIGINT NOT NULL, " +
+			"title VARCHAR(64) NOT NULL DEFAULT 'it''s', paid TINYINT(1) DEFAULT 0, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY (id), CONSTRAINT uk_user_title UNIQUE (user_id,title), " +
+			"FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+		psql.Postgres: "CREATE TABLE IF NOT EXISTS orders (id BIGINT GENERATED BY DEFAULT AS IDENTITY, user_id BIGINT NOT NULL, " +
+			"title VARCHAR(64) NOT NULL DEFAULT 'it''s', paid BOOLEAN DEFAULT FALSE, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY (id), CONSTRAINT uk_user_title UNIQUE (user_id,title), " +
+			"FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+		psql.SQLite: "CREATE TABLE IF NOT EXISTS orders (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, " +
+			"title VARCHAR(64) NOT NULL DEFAULT 'it''s', paid INTEGER DEFAULT 0, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, " +
+			"CONSTRAINT uk_user_title UNIQUE (user_id,title), " +
+			"FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+	}
+	for dialect, exQuery := range cases {
+		query, _, err := newStatement(dialect).ToSql()
+		if err != nil {
+			t.Error(err)
+		}
+		if query != exQuery {
+			t.Errorf("%s query not expected sql, query = %s", dialect, query)
+		}
+	}
+
+	query, _, err := psql.CreateTable("tags").
+		Column(psql.NewColumnDef("name", psql.VarcharType(32))).
+		Unique("", "name").
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "CREATE TABLE tags (name VARCHAR(32), UNIQUE (name))" {
+		t.Errorf("unnamed unique not expected sql, query = %s", query)
+	}
+
+	_, _, err = psql.CreateTable("tags").Column(psql.NewColumnDef("name", psql.VarcharType(32))).Unique("uk_name").ToSql()
+	if err == nil {
+		t.Error("unique without column should return error")
+	}
+}
+
+func TestAlterTable(t *testing.T) {
+	query, _, err := psql.AlterTable("orders").
+		AddColumn(psql.NewColumnDef("remark", psql.TextType)).
+		DropColumn("title").
+		ModifyColumn(psql.NewColumnDef("price", psql.DecimalType(10, 2)).NotNull().Default(0)).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery := "ALTER TABLE orders ADD COLUMN remark TEXT, DROP COLUMN title, MODIFY COLUMN price DECIMAL(10,2) NOT NULL DEFAULT 0"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = psql.NewAlterTable(psql.Postgres).Table("orders").
+		ModifyColumn(psql.NewColumnDef("price", psql.DecimalType(10, 2)).NotNull()).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery = "ALTER TABLE orders ALTER COLUMN price TYPE NUMERIC(10,2), ALTER COLUMN price SET NOT NULL"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = psql.NewAlterTable(psql.Postgres).Table("orders").
+		ModifyColumn(psql.NewColumnDef("price", psql.DecimalType(10, 2)).Nullable().DropDefault()).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery = "ALTER TABLE orders ALTER COLUMN price TYPE NUMERIC(10,2), ALTER COLUMN price DROP NOT NULL, ALTER COLUMN price DROP DEFAULT"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	_, _, err = psql.NewAlterTable(psql.SQLite).Table("orders").
+		ModifyColumn(psql.NewColumnDef("price", psql.DoubleType)).
+		ToSql()
+	if err == nil {
+		t.Error("sqlite modify column should return error")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	query, _, err := psql.CreateIndex("idx_user", "orders", "user_id", "created_at").Unique().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "CREATE UNIQUE INDEX idx_user ON orders (user_id,created_at)" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	_, _, err = psql.CreateIndex("idx_user", "orders", "user_id").IfNotExists().ToSql()
+	if err == nil {
+		t.Error("mysql create index if not exists should return error")
+	}
+
+	query, _, err = psql.NewCreateIndex(psql.Postgres).Name("idx_user").On("orders", "user_id").IfNotExists().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "CREATE INDEX IF NOT EXISTS idx_user ON orders (user_id)" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = psql.DropIndex("idx_user").On("orders").ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "DROP INDEX idx_user ON orders" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = psql.NewDropIndex(psql.SQLite).Name("idx_user").IfExists().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "DROP INDEX IF EXISTS idx_user" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = psql.DropTable("orders").IfExists().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "DROP TABLE IF EXISTS orders" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+}
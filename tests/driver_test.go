@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// fakeResult 测试用的结果，Columns 不为空时当作查询结果
+type fakeResult struct {
+	Columns  []string
+	Rows     [][]driver.Value
+	Affected int64
+}
+
+type fakeHandler func(query string, args []interface{}) (*fakeResult, error)
+
+// fakeConnector 一个不连数据库的 driver，记录执行过的 sql，结果由 handler 决定
+type fakeConnector struct {
+	sync.Mutex
+	handler fakeHandler
+	queries []string
+}
+
+func newFakeDB(handler fakeHandler) (*sql.DB, *fakeConnector) {
+	connector := &fakeConnector{handler: handler}
+	return sql.OpenDB(connector), connector
+}
+
+func (c *fakeConnector) Queries() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.queries...)
+}
+
+func (c *fakeConnector) handle(query string, named []driver.NamedValue) (*fakeResult, error) {
+	c.Lock()
+	c.queries = append(c.queries, query)
+	c.Unlock()
+
+	var args []interface{}
+	for _, nv := range named {
+		args = append(args, nv.Value)
+	}
+	if c.handler == nil {
+		return &fakeResult{}, nil
+	}
+	result, err := c.handler(query, args)
+	if result == nil {
+		result = &fakeResult{}
+	}
+	return result, err
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{connector: c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("fake driver must use connector")
+}
+
+type fakeConn struct {
+	connector *fakeConnector
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver not support prepare")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	_, err := c.connector.handle("BEGIN", nil)
+	return &fakeTx{connector: c.connector}, err
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.connector.handle(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(result.Affected), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.connector.handle(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{result: result}, nil
+}
+
+type fakeTx struct {
+	connector *fakeConnector
+}
+
+func (tx *fakeTx) Commit() error {
+	_, err := tx.connector.handle("COMMIT", nil)
+	return err
+}
+
+func (tx *fakeTx) Rollback() error {
+	_, err := tx.connector.handle("ROLLBACK", nil)
+	return err
+}
+
+type fakeRows struct {
+	result *fakeResult
+	index  int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.Columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.index])
+	r.index++
+	return nil
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yongpi/putil/plog"
+	"github.com/yongpi/putil/psql"
+)
+
+type recordHook struct {
+	events []psql.QueryEvent
+}
+
+func (h *recordHook) Before(ctx context.Context, event *psql.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *recordHook) After(ctx context.Context, event *psql.QueryEvent) {
+	h.events = append(h.events, *event)
+}
+
+func TestQueryHook(t *testing.T) {
+	db, _ := newFakeDB(func(query string, args []interface{}) (*fakeResult, error) {
+		return &fakeResult{Columns: []string{"id"}, Affected: 2}, nil
+	})
+	defer db.Close()
+
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.WARN)
+	logger.Out = &buffer
+
+	record := &recordHook{}
+	executor := psql.WithHooks(db, record, psql.NewSlowQueryHook(0,
+		psql.SensitiveColumns("password", "token"), psql.SlowQueryLogger(logger)))
+
+	ctx := context.Background()
+	update := psql.Update("users").Set("password", "secret").Set("name", "sss").Where(psql.Eq{"u.token": "t1", "id": 1})
+	if _, err := psql.Exec(ctx, executor, update); err != nil {
+		t.Error(err)
+	}
+	rows, err := psql.Query(ctx, executor, psql.Select("id").From("users"))
+	if err != nil {
+		t.Error(err)
+	}
+	rows.Close()
+	if _, err = psql.Exec(ctx, executor, psql.Insert("users").Column("name", "password").Value("a", "p1").Value("b", "p2")); err != nil {
+		t.Error(err)
+	}
+
+	hashed := psql.Update("users").Set("password", psql.Expr("SHA2(?,256)", "secret2")).Where("name = ?", "sss").Where(psql.Eq{"id": 1})
+	if _, err = psql.Exec(ctx, executor, hashed); err != nil {
+		t.Error(err)
+	}
+	if _, err = executor.ExecContext(ctx, "UPDATE users SET password = ? WHERE id = ?", "secret3", 1); err != nil {
+		t.Error(err)
+	}
+
+	if len(record.events) != 5 {
+		t.Fatalf("events not expected len, events = %#v", record.events)
+	}
+	first := record.events[0]
+	if first.Statement != update || first.Kind != "UPDATE" || first.RowsAffected != 2 || first.Err != nil {
+		t.Errorf("update event not expected, event = %#v", first)
+	}
+	if record.events[1].Kind != "SELECT" || record.events[1].RowsAffected != -1 {
+		t.Errorf("select event not expected, event = %#v", record.events[1])
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("slow query log not expected, log = %s", buffer.String())
+	}
+	if !strings.Contains(lines[0], "args = [*** sss 1 ***]") || strings.Contains(lines[0], "secret") {
+		t.Errorf("update args should be redacted, log = %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "args = [a *** b ***]") {
+		t.Errorf("insert args should be redacted, log = %s", lines[2])
+	}
+	// 表达式里的参数属于 SET 的列，原生条件和直接执行的 sql 对应不上列，全部替换
+	if !strings.Contains(lines[3], "args = [*** *** 1]") || strings.Contains(lines[3], "secret2") {
+		t.Errorf("expression and raw args should be redacted, log = %s", lines[3])
+	}
+	if !strings.Contains(lines[4], "args = [*** ***]") || strings.Contains(lines[4], "secret3") {
+		t.Errorf("raw sql args should be redacted, log = %s", lines[4])
+	}
+}
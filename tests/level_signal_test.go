@@ -0,0 +1,32 @@
+//go:build aix || android || darwin || dragonfly || freebsd || hurd || illumos || ios || linux || netbsd || openbsd || solaris
+
+package tests
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+func TestLevelOnSignal(t *testing.T) {
+	logger := plog.NewLogger(plog.INFO)
+	stop := logger.LevelOnSignal()
+	defer stop()
+
+	waitLevel := func(level plog.LevelType) {
+		deadline := time.Now().Add(time.Second)
+		for logger.GetLevel() != level && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if logger.GetLevel() != level {
+			t.Fatalf("level not expected, level = %s, expected = %s", logger.GetLevel(), level)
+		}
+	}
+
+	_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(plog.DEBUG)
+	_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitLevel(plog.INFO)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := plog.ParseLevels(" conn_pool=debug, psql=WARN ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 2 || levels["conn_pool"] != plog.DEBUG || levels["psql"] != plog.WARN {
+		t.Errorf("levels not expected, levels = %v", levels)
+	}
+
+	for _, spec := range []string{"psql", "psql=verbose", "=debug"} {
+		if _, err = plog.ParseLevels(spec); err == nil {
+			t.Errorf("spec %q should be invalid", spec)
+		}
+	}
+}
+
+func TestPackageLevels(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.WARN)
+	logger.Out = &buffer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+	logger.AutoCaller = false
+	entry := logger.WithTime(logTime)
+
+	if err := logger.SetLevelsString("conn_pool=debug"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("other package")
+	if buffer.Len() != 0 {
+		t.Errorf("other package should use base level, output = %s", buffer.String())
+	}
+
+	if err := logger.SetLevelsString("putil=error,putil/tests=debug"); err != nil {
+		t.Fatal(err)
+	}
+	entry.Debug("tests")
+	entry.WithField("k", "v").Debug("entry")
+	if buffer.String() != "level=debug ts=2020-01-02T03:04:05Z msg=tests\nlevel=debug ts=2020-01-02T03:04:05Z msg=entry k=v\n" {
+		t.Errorf("longest key should win, output = %s", buffer.String())
+	}
+
+	buffer.Reset()
+	logger.SetLevel(plog.DEBUG)
+	if err := logger.SetLevelsString("github.com/yongpi/putil=error"); err != nil {
+		t.Fatal(err)
+	}
+	entry.Warn("module")
+	entry.Error("module")
+	if buffer.String() != "level=error ts=2020-01-02T03:04:05Z msg=module\n" {
+		t.Errorf("module override not expected, output = %s", buffer.String())
+	}
+
+	buffer.Reset()
+	logger.SetLevels(nil)
+	logger.Debug("cleared")
+	if buffer.Len() == 0 {
+		t.Errorf("levels should be cleared")
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	logger := plog.NewLogger(plog.INFO)
+	logger.SetLevelFor(plog.WARN, time.Hour)
+	logger.SetLevelFor(plog.DEBUG, 20*time.Millisecond)
+	if logger.GetLevel() != plog.DEBUG {
+		t.Fatalf("level should be override, level = %s", logger.GetLevel())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for logger.GetLevel() != plog.INFO && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if logger.GetLevel() != plog.INFO {
+		t.Errorf("level should revert to the original, level = %s", logger.GetLevel())
+	}
+
+	logger.SetLevelFor(plog.DEBUG, 20*time.Millisecond)
+	logger.SetLevel(plog.ERROR)
+	time.Sleep(50 * time.Millisecond)
+	if logger.GetLevel() != plog.ERROR {
+		t.Errorf("SetLevel should cancel override, level = %s", logger.GetLevel())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := plog.NewLogger(plog.INFO)
+	handler := logger.LevelHandler()
+
+	cases := []struct {
+		method string
+		body   string
+		code   int
+		resp   string
+	}{
+		{http.MethodGet, "", http.StatusOK, `{"level":"info"}`},
+		{http.MethodPut, `{"level":"debug"}`, http.StatusOK, `{"level":"debug"}`},
+		{http.MethodPut, `{"level":"verbose"}`, http.StatusBadRequest, `{"error":"level \"verbose\" is invalid"}`},
+		{http.MethodPut, `{"level":"warn","duration":"-1s"}`, http.StatusBadRequest, `{"error":"duration \"-1s\" is invalid"}`},
+		{http.MethodPut, `{"level":"warn","duration":"1h"}`, http.StatusOK, `{"level":"warn"}`},
+		{http.MethodPost, "", http.StatusMethodNotAllowed, `{"error":"method POST not allowed"}`},
+	}
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(c.method, "/level", strings.NewReader(c.body)))
+		if recorder.Code != c.code || strings.TrimSpace(recorder.Body.String()) != c.resp {
+			t.Errorf("%s %s not expected, code = %d, resp = %s", c.method, c.body, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yongpi/putil/psql"
+)
+
+func TestLint(t *testing.T) {
+	linter := psql.NewLinter(psql.IndexedColumns("created_at", "email"))
+
+	st := psql.Select("*").
+		From("users").
+		Where(psql.Like{"name": "%foo"}).
+		Where(psql.Or{psql.Eq{"status": 1}, psql.Eq{"type": 2}}).
+		Where("DATE(created_at) = ?", "2026-01-01").
+		Where(psql.Eq{"LOWER(email)": "a@b.c", "UPPER(name)": "A"})
+
+	findings := linter.Lint(st)
+	exRules := []psql.LintRule{
+		psql.RuleSelectStar,
+		psql.RuleMissingLimit,
+		psql.RuleLeadingWildcard,
+		psql.RuleOrDifferentColumns,
+		psql.RuleFunctionOnColumn,
+		psql.RuleFunctionOnColumn,
+	}
+	if len(findings) != len(exRules) {
+		t.Fatalf("findings not expected len, findings = %#v", findings)
+	}
+	for index, finding := range findings {
+		if finding.Rule != exRules[index] {
+			t.Errorf("finding not expected rule, finding = %#v", finding)
+		}
+	}
+
+	clean := psql.Select("id").From("users").Where(psql.Like{"name": "foo%"}).Where(psql.Or{psql.Eq{"id": 1}, psql.Eq{"id": 2}}).Limit(10)
+	if findings = linter.Lint(clean); len(findings) != 0 {
+		t.Errorf("findings should be empty, findings = %#v", findings)
+	}
+	if findings = linter.Lint(psql.Select().ColumnExpr(psql.Count("*")).From("users")); len(findings) != 0 {
+		t.Errorf("aggregate select should not need limit, findings = %#v", findings)
+	}
+
+	// FilterSchema 生成的 LIKE 条件同样检查
+	schema := psql.FilterSchema{
+		"name":   {Column: "name", Type: psql.StringField, Ops: []psql.FilterOp{psql.OpLike}},
+		"status": {Column: "status", Type: psql.StringField},
+	}
+	cond, err := schema.ParseMap(map[string]string{"name_like": "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings = linter.Lint(psql.Select("id").From("users").Where(psql.Or{cond, psql.Eq{"status": "a"}}).Limit(10))
+	if len(findings) != 2 || findings[0].Rule != psql.RuleLeadingWildcard || findings[1].Rule != psql.RuleOrDifferentColumns || !strings.Contains(findings[1].Message, "name,status") {
+		t.Errorf("filter like findings not expected, findings = %#v", findings)
+	}
+
+	// 开发环境把 SELECT * 当成错误
+	strict := psql.NewLinter(psql.RuleSeverity(psql.RuleSelectStar, psql.SeverityError), psql.RuleSeverity(psql.RuleMissingLimit, psql.SeverityOff))
+	db, connector := newFakeDB(nil)
+	defer db.Close()
+	executor := psql.WithLinter(db, strict)
+
+	_, err = psql.Query(context.Background(), executor, psql.Select("*").From("users"))
+	var lintErr *psql.LintError
+	if !errors.As(err, &lintErr) || len(lintErr.Findings) != 1 || lintErr.Findings[0].Rule != psql.RuleSelectStar {
+		t.Errorf("lint error expected, err = %v", err)
+	}
+	if len(connector.Queries()) != 0 {
+		t.Errorf("failed statement should not be executed, queries = %v", connector.Queries())
+	}
+
+	rows, err := psql.Query(context.Background(), executor, psql.Select("id").From("users"))
+	if err != nil {
+		t.Error(err)
+	} else {
+		rows.Close()
+	}
+}
@@ -1,8 +1,13 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/yongpi/putil/plog"
 )
@@ -15,3 +20,273 @@ func TestLog(t *testing.T) {
 	plog.Fatal("root fatal")
 	print("fatal")
 }
+
+type fieldHook struct {
+	userID interface{}
+}
+
+func (h *fieldHook) On(entry *plog.Entry) {
+	h.userID, _ = entry.Field("user_id")
+}
+
+func TestEntryFields(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	hook := &fieldHook{}
+	logger.Hooks = plog.Hooks{plog.INFO: []plog.Hook{hook}}
+
+	base := logger.WithField("user_id", 42)
+	base.WithFields(plog.Fields{"path": "/a b", "code": 200}).WithField("user_id", 43).Info("request done")
+	base.WithError(errors.New("boom")).Info("request failed")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log not expected, log = %s", buffer.String())
+	}
+	if !strings.HasSuffix(lines[0], `] request done user_id=43 code=200 path="/a b"`) {
+		t.Errorf("fields not expected, line = %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "] request failed user_id=42, err = boom") {
+		t.Errorf("fields not expected, line = %s", lines[1])
+	}
+	if hook.userID != 42 {
+		t.Errorf("hook should read fields, user_id = %v", hook.userID)
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.JSONFormatter{MessageKey: "message"}
+
+	logger.WithFields(plog.Fields{"user_id": 42, "ratio": 0.5, "ok": true, "tags": []string{"a"}, "level": "dup"}).
+		WithError(errors.New("bad \"thing\"")).
+		Error("line1\nline2 \x01 \xff")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatalf("output is not json, output = %s, err = %v", buffer.String(), err)
+	}
+
+	exData := map[string]interface{}{
+		"level":        "error",
+		"message":      "line1\nline2 \x01 �",
+		"error":        `bad "thing"`,
+		"user_id":      float64(42),
+		"ratio":        0.5,
+		"ok":           true,
+		"fields.level": "dup",
+	}
+	for key, value := range exData {
+		if data[key] != value {
+			t.Errorf("json key %s not expected, value = %#v, output = %s", key, data[key], buffer.String())
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, data["time"].(string)); err != nil {
+		t.Errorf("time not expected format, time = %v", data["time"])
+	}
+	if caller, _ := data["caller"].(string); !strings.Contains(caller, "log_test.go:") {
+		t.Errorf("caller not expected, caller = %v", data["caller"])
+	}
+	if !strings.HasPrefix(buffer.String(), `{"level":"error","time":`) {
+		t.Errorf("key order not expected, output = %s", buffer.String())
+	}
+}
+
+type indentMarshaler struct{}
+
+func (indentMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte("{\n  \"a\": 1\n}"), nil
+}
+
+type pointerMarshaler struct {
+	value int
+}
+
+func (p *pointerMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.value)
+}
+
+func TestJSONFormatterFallback(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.JSONFormatter{}
+
+	var nilMarshaler *pointerMarshaler
+	logger.WithFields(plog.Fields{"ch": make(chan int), "indent": indentMarshaler{}, "nil": nilMarshaler}).Info("fallback")
+
+	output := buffer.String()
+	if strings.Count(output, "\n") != 1 || !strings.HasSuffix(output, "}\n") {
+		t.Fatalf("output should be one line, output = %q", output)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &data); err != nil {
+		t.Fatalf("output is not json, output = %s, err = %v", output, err)
+	}
+	if data["msg"] != "fallback" || data["nil"] != nil || !strings.HasPrefix(data["ch"].(string), "0x") {
+		t.Errorf("fallback not expected, output = %s", output)
+	}
+	if !strings.Contains(output, `"indent":{"a":1}`) {
+		t.Errorf("marshaler output should be compact, output = %s", output)
+	}
+}
+
+// valueError 值接收者，值为 nil 的 *valueError 调用 Error 会 panic
+type valueError struct{}
+
+func (valueError) Error() string {
+	return "value error"
+}
+
+func TestNilFieldValue(t *testing.T) {
+	var nilTime *time.Time
+	var nilErr *valueError
+	formatters := []plog.Formatter{&plog.DefaultFormatter{}, &plog.LogfmtFormatter{}, &plog.ConsoleFormatter{}, &plog.JSONFormatter{}}
+	for _, formatter := range formatters {
+		var buffer bytes.Buffer
+		logger := plog.NewLogger(plog.INFO)
+		logger.Out = &buffer
+		logger.Format = formatter
+
+		logger.WithField("t", nilTime).WithError(nilErr).Info("hi")
+		if !strings.Contains(buffer.String(), "<nil>") || strings.Count(buffer.String(), "\n") != 1 {
+			t.Errorf("%T nil value not expected, line = %q", formatter, buffer.String())
+		}
+	}
+}
+
+// logTime 测试里固定日志时间，输出和当前时间无关
+var logTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestLogfmtFormatter(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+
+	logger.WithField("user id", 42).WithField("path", "/a b").WithTime(logTime).Info(`say "hi"`)
+
+	exLine := `level=info ts=2020-01-02T03:04:05Z msg="say \"hi\"" user_id=42 path="/a b"` + "\n"
+	if buffer.String() != exLine {
+		t.Errorf("logfmt not expected, line = %s", buffer.String())
+	}
+
+	// 控制字符需要转义，不能伪造出新的一行
+	buffer.Reset()
+	logger.WithField("v", "a\rlevel=error\x1b\u2028").WithTime(logTime).Info("hi\tthere")
+	exLine = `level=info ts=2020-01-02T03:04:05Z msg="hi\tthere" v="a\rlevel=error\x1b\u2028"` + "\n"
+	if buffer.String() != exLine {
+		t.Errorf("control characters not escaped, line = %q", buffer.String())
+	}
+}
+
+func TestConsoleFormatter(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	formatter := &plog.ConsoleFormatter{MessageWidth: 10}
+	logger.Format = formatter
+
+	logger.WithField("k", "v").Warn("hi")
+	if strings.Contains(buffer.String(), "\x1b[") {
+		t.Errorf("colors should be disabled when out is not terminal, line = %q", buffer.String())
+	}
+	if !strings.HasPrefix(buffer.String(), "WARN  [") || !strings.HasSuffix(buffer.String(), "] hi         k=v\n") {
+		t.Errorf("console not expected, line = %q", buffer.String())
+	}
+
+	buffer.Reset()
+	formatter.ForceColors = true
+	logger.Warn("hi")
+	if !strings.HasPrefix(buffer.String(), "\x1b[33mWARN \x1b[0m [") {
+		t.Errorf("console should be colored, line = %q", buffer.String())
+	}
+}
+
+func TestNamedLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+	logger.AutoCaller = false
+
+	child := logger.Named("db").With(plog.Field{Key: "shard", Value: 1}).Named("pool")
+	if child.Name() != "db.pool" {
+		t.Errorf("name not expected, name = %s", child.Name())
+	}
+	child.WithField("k", "v").WithTime(logTime).Info("hi")
+	child.Debug("hidden")
+	if buffer.String() != "level=info ts=2020-01-02T03:04:05Z logger=db.pool msg=hi shard=1 k=v\n" {
+		t.Errorf("child output not expected, output = %s", buffer.String())
+	}
+
+	// 子 logger 共享父 logger 的级别和 Out
+	buffer.Reset()
+	var other bytes.Buffer
+	logger.SetLevel(plog.DEBUG)
+	logger.Out = &other
+	child.WithTime(logTime).Debug("shown")
+	if child.GetLevel() != plog.DEBUG || buffer.Len() != 0 || other.String() != "level=debug ts=2020-01-02T03:04:05Z logger=db.pool msg=shown shard=1\n" {
+		t.Errorf("child should share parent, output = %s", other.String())
+	}
+
+	// 名字可以用来覆盖级别
+	other.Reset()
+	logger.SetLevels(map[string]plog.LevelType{"pool": plog.ERROR})
+	child.Warn("hidden")
+	logger.WithTime(logTime).Warn("shown")
+	if other.String() != "level=warn ts=2020-01-02T03:04:05Z msg=shown\n" {
+		t.Errorf("name level override not expected, output = %s", other.String())
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+	logger.AutoCaller = false
+
+	ctx := plog.ContextWithRequestID(context.Background(), "req-1")
+	ctx = plog.ContextWithTraceParent(ctx, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx = plog.ContextWithUserID(ctx, 42)
+	ctx = logger.NewContext(ctx, plog.Field{Key: "route", Value: "/a"})
+	ctx = plog.NewContext(ctx, plog.Field{Key: "user_id", Value: 7})
+
+	plog.FromContext(ctx).WithField("k", "v").WithTime(logTime).Info("hi")
+	exLine := "level=info ts=2020-01-02T03:04:05Z msg=hi request_id=req-1 trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 user_id=7 route=/a k=v\n"
+	if buffer.String() != exLine {
+		t.Errorf("context fields not expected, line = %s", buffer.String())
+	}
+
+	buffer.Reset()
+	plog.RegisterContextExtractor("tenant", func(ctx context.Context) []plog.Field {
+		return []plog.Field{{Key: "tenant", Value: "t1"}}
+	})
+	defer plog.UnregisterContextExtractor("tenant")
+	logger.WithContext(context.Background()).WithTime(logTime).Info("hi")
+	if buffer.String() != "level=info ts=2020-01-02T03:04:05Z msg=hi tenant=t1\n" {
+		t.Errorf("custom extractor not expected, line = %s", buffer.String())
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	cases := map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":    true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-ab": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-ab": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":    false,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":    false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":    false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":    false,
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01":                    false,
+	}
+	for traceParent, ok := range cases {
+		if _, _, got := plog.ParseTraceParent(traceParent); got != ok {
+			t.Errorf("parse %s not expected, ok = %v", traceParent, got)
+		}
+	}
+}
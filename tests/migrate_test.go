@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/yongpi/putil/psql"
+	"github.com/yongpi/putil/psql/migrate"
+)
+
+// migrationStore 模拟 schema_migrations 表和锁表
+type migrationStore struct {
+	records  map[int64][]driver.Value
+	locked   bool
+	owner    interface{}
+	lockedAt int64
+}
+
+func (s *migrationStore) handle(query string, args []interface{}) (*fakeResult, error) {
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations_lock"):
+		if s.locked {
+			return nil, errors.New("duplicate key")
+		}
+		s.locked, s.owner, s.lockedAt = true, args[1], args[2].(int64)
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations_lock"):
+		switch {
+		case strings.Contains(query, "owner"):
+			s.locked = s.locked && s.owner != args[1]
+		case strings.Contains(query, "locked_at"):
+			s.locked = s.locked && s.lockedAt >= args[1].(int64)
+		}
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations "):
+		s.records[args[0].(int64)] = []driver.Value{args[0], args[1], args[2], args[3]}
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations "):
+		delete(s.records, args[0].(int64))
+	case strings.HasPrefix(query, "SELECT version"):
+		result := &fakeResult{Columns: []string{"version", "name", "checksum", "applied_at"}}
+		for _, record := range s.records {
+			result.Rows = append(result.Rows, record)
+		}
+		return result, nil
+	case strings.HasPrefix(query, "BROKEN"):
+		return nil, errors.New("syntax error")
+	}
+	return nil, nil
+}
+
+func TestMigrator(t *testing.T) {
+	store := &migrationStore{records: make(map[int64][]driver.Value)}
+	db, connector := newFakeDB(store.handle)
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER)")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"migrations/0002_add_name.up.sql":       {Data: []byte("ALTER TABLE users ADD COLUMN name TEXT")},
+		"migrations/0002_add_name.down.sql":     {Data: []byte("ALTER TABLE users DROP COLUMN name")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	m := migrate.New(db, migrate.Dialect(psql.SQLite))
+	if err := m.LoadFS(fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Register(3, "seed", func(ctx context.Context, executor psql.Executor) error {
+		_, err := psql.Exec(ctx, executor, psql.Insert("users").Column("id").Value(1))
+		return err
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 3 || store.locked {
+		t.Errorf("up not expected, records = %v, locked = %v", store.records, store.locked)
+	}
+
+	var executed []string
+	for _, query := range connector.Queries() {
+		if strings.HasPrefix(query, "CREATE TABLE users") || strings.HasPrefix(query, "ALTER TABLE users") ||
+			strings.HasPrefix(query, "INSERT INTO users") || query == "BEGIN" || query == "COMMIT" {
+			executed = append(executed, query)
+		}
+	}
+	exExecuted := []string{
+		"BEGIN", "CREATE TABLE users (id INTEGER)", "COMMIT",
+		"BEGIN", "ALTER TABLE users ADD COLUMN name TEXT", "COMMIT",
+		"BEGIN", "INSERT INTO users (id) VALUES (?)", "COMMIT",
+	}
+	if strings.Join(executed, ";") != strings.Join(exExecuted, ";") {
+		t.Errorf("executed not expected, executed = %v", executed)
+	}
+
+	// 3 没有 down，回滚需要报错
+	if err = m.Down(ctx, 1); !errors.Is(err, migrate.ErrNoDown) {
+		t.Errorf("no down error expected, err = %v", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || !statuses[0].Applied || statuses[0].Name != "create_users" || statuses[0].Modified {
+		t.Errorf("status not expected, statuses = %+v", statuses)
+	}
+
+	delete(store.records, 3)
+	if err = m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 2 {
+		t.Errorf("redo not expected, records = %v", store.records)
+	}
+	if err = m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("down not expected, records = %v", store.records)
+	}
+
+	if err = m.Down(ctx, -1); err == nil {
+		t.Error("negative down count should return error")
+	}
+
+	// 别的实例正在迁移
+	store.locked, store.owner, store.lockedAt = true, "other", time.Now().Unix()
+	if err = m.Up(ctx); !errors.Is(err, migrate.ErrLocked) {
+		t.Errorf("locked error expected, err = %v", err)
+	}
+	if !store.locked || store.owner != "other" {
+		t.Error("lock of other owner should not be released")
+	}
+
+	// 持有者崩溃，锁过期之后可以被抢占
+	store.lockedAt = time.Now().Add(-2 * time.Hour).Unix()
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if store.locked {
+		t.Error("lock should be released")
+	}
+	delete(store.records, 3)
+	if err = m.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已经执行过的文件被修改，down 文件的修改也算
+	if err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	modified := migrate.New(db, migrate.Dialect(psql.SQLite))
+	fsys["migrations/0002_add_name.down.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users DROP name")}
+	if err = modified.LoadFS(fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if statuses, err = modified.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].Modified || !statuses[1].Modified {
+		t.Errorf("down file change not detected, statuses = %+v", statuses)
+	}
+
+	store.records[1] = []driver.Value{int64(1), "create_users", "changed", int64(0)}
+	if err = m.Up(ctx); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("checksum mismatch error expected, err = %v", err)
+	}
+	if store.locked {
+		t.Error("lock should be released")
+	}
+}
+
+func TestMigrationStatements(t *testing.T) {
+	store := &migrationStore{records: make(map[int64][]driver.Value)}
+	db, connector := newFakeDB(store.handle)
+	defer db.Close()
+
+	up := "-- 初始化\nCREATE TABLE users (id INTEGER, name TEXT DEFAULT 'a;b');\n" +
+		"/* 种子数据; */ INSERT INTO users (id) VALUES (1);\n" +
+		"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;\n-- end\n"
+	fsys := fstest.MapFS{"migrations/0001_init.up.sql": {Data: []byte(up)}}
+
+	m := migrate.New(db, migrate.Dialect(psql.SQLite))
+	if err := m.LoadFS(fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var executed []string
+	for _, query := range connector.Queries() {
+		if strings.Contains(query, "users (") || strings.HasPrefix(query, "CREATE FUNCTION") {
+			executed = append(executed, query)
+		}
+	}
+	exExecuted := []string{
+		"-- 初始化\nCREATE TABLE users (id INTEGER, name TEXT DEFAULT 'a;b')",
+		"/* 种子数据; */ INSERT INTO users (id) VALUES (1)",
+		"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql",
+	}
+	if strings.Join(executed, "|") != strings.Join(exExecuted, "|") {
+		t.Errorf("statements not expected, executed = %q", executed)
+	}
+
+	// 没有 up 的迁移不能注册，否则会被记录成已经执行
+	if err := m.Add(&migrate.Migration{Version: 2, Name: "empty", UpSql: " \n"}); err == nil {
+		t.Error("migration without up should return error")
+	}
+	if err := m.Register(3, "empty", nil, nil); err == nil {
+		t.Error("migration without up func should return error")
+	}
+}
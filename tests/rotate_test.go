@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+func readLogDir(t *testing.T, dir string) map[string]string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reader io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			if reader, err = gzip.NewReader(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := io.ReadAll(reader)
+		_ = file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+func TestRotateWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	clock := func() time.Time { return now }
+
+	writer, err := plog.NewRotateWriter(filepath.Join(dir, "app.log"), plog.MaxSize(30), plog.MaxBackups(2), plog.RotateClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = writer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: "2006"}
+
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		now = now.Add(time.Second)
+		logger.WithTime(now).Info(msg)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readLogDir(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("file count not expected, files = %v", files)
+	}
+	if files["app.log"] != "level=info ts=2026 msg=d\n" {
+		t.Errorf("current file not expected, content = %q", files["app.log"])
+	}
+	if files["app-2026-01-01T00-00-03.000.log"] != "level=info ts=2026 msg=b\n" || files["app-2026-01-01T00-00-04.000.log"] != "level=info ts=2026 msg=c\n" {
+		t.Errorf("backups not expected, files = %v", files)
+	}
+}
+
+func TestRotateWriterSameTime(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	clock := func() time.Time { return now }
+
+	writer, err := plog.NewRotateWriter(filepath.Join(dir, "app.log"), plog.MaxBackups(2), plog.RotateClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一毫秒内切分三次，备份不会互相覆盖，清理时序号大的更新
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		if _, err = writer.Write([]byte(msg + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		if msg != "d" {
+			if err = writer.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readLogDir(t, dir)
+	if len(files) != 3 || files["app.log"] != "d\n" || files["app-2026-01-01T00-00-00.000.1.log"] != "b\n" || files["app-2026-01-01T00-00-00.000.2.log"] != "c\n" {
+		t.Errorf("backups not expected, files = %v", files)
+	}
+}
+
+func TestRotateWriterPeriod(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 23, 59, 0, 0, time.Local)
+	clock := func() time.Time { return now }
+
+	writer, err := plog.NewRotateWriter(filepath.Join(dir, "app.log"), plog.RotateEvery(plog.RotateDaily), plog.Compress(), plog.RotateClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = writer.Write([]byte("day1\n"))
+	_, _ = writer.Write([]byte("day1 again\n"))
+	now = now.Add(2 * time.Minute)
+	_, _ = writer.Write([]byte("day2\n"))
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readLogDir(t, dir)
+	if len(files) != 2 || files["app.log"] != "day2\n" || files["app-2026-01-02T00-01-00.000.log.gz"] != "day1\nday1 again\n" {
+		t.Errorf("daily rotate not expected, files = %v", files)
+	}
+}
+
+func TestRotateWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	writer, err := plog.NewRotateWriter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	_, _ = writer.Write([]byte("before\n"))
+	// 模拟外部 logrotate 把文件移走
+	if err = os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = writer.Write([]byte("after\n"))
+
+	files := readLogDir(t, dir)
+	if files["app.log.1"] != "before\n" || files["app.log"] != "after\n" {
+		t.Errorf("reopen not expected, files = %v", files)
+	}
+}
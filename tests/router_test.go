@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/psql"
+)
+
+func TestRouter(t *testing.T) {
+	primary, primaryConn := newFakeDB(nil)
+	defer primary.Close()
+	replica1, replicaConn1 := newFakeDB(nil)
+	defer replica1.Close()
+
+	var broken, badQuery bool
+	replica2, replicaConn2 := newFakeDB(func(query string, args []interface{}) (*fakeResult, error) {
+		if badQuery {
+			return nil, errors.New("syntax error")
+		}
+		if broken {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+		}
+		return &fakeResult{Columns: []string{"id"}}, nil
+	})
+	defer replica2.Close()
+
+	router := psql.NewRouter(primary, []*sql.DB{replica1, replica2}, psql.MaxFailures(2), psql.RetryAfter(time.Hour))
+	ctx := context.Background()
+	query := func(ctx context.Context) error {
+		rows, err := router.Query(ctx, psql.Select("id").From("test"))
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := query(ctx); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(replicaConn1.Queries()) != 2 || len(replicaConn2.Queries()) != 2 || len(primaryConn.Queries()) != 0 {
+		t.Errorf("select should round robin on replicas, replica1 = %v, replica2 = %v", replicaConn1.Queries(), replicaConn2.Queries())
+	}
+
+	if _, err := router.Exec(ctx, psql.Update("test").Set("name", "sss")); err != nil {
+		t.Error(err)
+	}
+	if err := query(psql.ForcePrimary(ctx)); err != nil {
+		t.Error(err)
+	}
+	if len(primaryConn.Queries()) != 2 {
+		t.Errorf("write and force primary should use primary, primary = %v", primaryConn.Queries())
+	}
+
+	// 语句本身的错误原样返回，不回退也不算从库故障
+	badQuery = true
+	for i := 0; i < 4; i++ {
+		err := query(ctx)
+		if i%2 == 0 && err != nil || i%2 == 1 && err == nil {
+			t.Errorf("only replica2 should fail, i = %d, err = %v", i, err)
+		}
+	}
+	badQuery = false
+	if len(replicaConn2.Queries()) != 4 || len(primaryConn.Queries()) != 2 {
+		t.Errorf("bad query should not fall back, primary = %v, replica2 = %v", primaryConn.Queries(), replicaConn2.Queries())
+	}
+
+	// replica2 连接出错时回退到主库，连续失败两次之后不再使用
+	broken = true
+	for i := 0; i < 6; i++ {
+		if err := query(ctx); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(replicaConn2.Queries()) != 6 || len(primaryConn.Queries()) != 4 {
+		t.Errorf("broken replica should fall back and be skipped, primary = %v, replica2 = %v", primaryConn.Queries(), replicaConn2.Queries())
+	}
+}
+
+func TestRouterLeastInFlight(t *testing.T) {
+	primary, _ := newFakeDB(nil)
+	defer primary.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	replica1, replicaConn1 := newFakeDB(func(query string, args []interface{}) (*fakeResult, error) {
+		started <- struct{}{}
+		<-release
+		return &fakeResult{Columns: []string{"id"}}, nil
+	})
+	defer replica1.Close()
+	replica2, replicaConn2 := newFakeDB(nil)
+	defer replica2.Close()
+
+	router := psql.NewRouter(primary, []*sql.DB{replica1, replica2}, psql.Strategy(psql.LeastInFlight))
+	ctx := context.Background()
+	done := make(chan error)
+	go func() {
+		rows, err := router.Query(ctx, psql.Select("id").From("test"))
+		if err == nil {
+			err = rows.Close()
+		}
+		done <- err
+	}()
+	<-started
+
+	// replica1 有一个查询还没返回，新的查询走 replica2
+	for i := 0; i < 2; i++ {
+		rows, err := router.Query(ctx, psql.Select("id").From("test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+	if len(replicaConn1.Queries()) != 1 || len(replicaConn2.Queries()) != 2 {
+		t.Errorf("least in flight not expected, replica1 = %v, replica2 = %v", replicaConn1.Queries(), replicaConn2.Queries())
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/psql"
+)
+
+func TestShard(t *testing.T) {
+	for _, count := range []int64{0, -1} {
+		if _, err := psql.ModShard("user_id", count); err == nil {
+			t.Errorf("invalid mod shard count should return error, count = %d", count)
+		}
+	}
+
+	mod, err := psql.ModShard("user_id", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	builder := psql.NewSqlBuilder(psql.Question).
+		WithShard("orders", mod).
+		WithShard("logs", psql.MonthShard("created_month", from, from.AddDate(0, 2, 0)))
+
+	items := []struct {
+		st      psql.SqlStatement
+		exQuery string
+	}{
+		{
+			builder.Select("id").From("orders o").Where(psql.Eq{"o.user_id": 130, "status": 1}),
+			"SELECT id FROM orders_02 o  Where o.user_id = ? And status = ?",
+		},
+		{
+			builder.Select("id").From("orders").Where(psql.And{psql.Eq{"status": 1}, psql.Eq{"user_id": []int{1, 65}}}),
+			"SELECT id FROM orders_01  Where (status = ? AND user_id IN (?,?))",
+		},
+		{
+			builder.Insert("orders").Column("id", "user_id").Value(1, 63).Value(2, 127),
+			"INSERT INTO orders_63 (id,user_id) VALUES (?,?),(?,?)",
+		},
+		{
+			builder.Update("orders").Set("status", 2).Where(psql.Eq{"user_id": 3}),
+			"UPDATE orders_03 SET status=? WHERE user_id = ?",
+		},
+		{
+			builder.Delete("logs").Where(psql.Eq{"created_month": from.AddDate(0, 1, 0)}),
+			"DELETE FROM logs_202602 WHERE created_month = ?",
+		},
+		{
+			builder.Select("id").From("users"),
+			"SELECT id FROM users ",
+		},
+	}
+	for _, item := range items {
+		query, _, err := item.st.ToSql()
+		if err != nil {
+			t.Error(err)
+		}
+		if query != item.exQuery {
+			t.Errorf("query not expected sql, query = %s", query)
+		}
+	}
+
+	bad := []psql.SqlStatement{
+		builder.Select("id").From("orders").Where(psql.Eq{"status": 1}),
+		builder.Select("id").From("orders").Where(psql.Or{psql.Eq{"user_id": 1}, psql.Eq{"user_id": 2}}),
+		builder.Select("id").From("orders").Where(psql.Eq{"user_id": []int{1, 2}}),
+		builder.Insert("orders").Column("id", "user_id").Value(1, 1).Value(2, 2),
+		builder.Insert("orders").Column("id").Value(1),
+	}
+	for _, st := range bad {
+		if _, _, err := st.ToSql(); err == nil {
+			t.Errorf("statement should return error, statement = %#v", st)
+		}
+	}
+
+	list, err := builder.Select("id").From("logs l").Where(psql.Eq{"l.level": "error"}).FanOut()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	for _, st := range list {
+		query, _, err := st.ToSql()
+		if err != nil {
+			t.Error(err)
+		}
+		queries = append(queries, query)
+	}
+	exQueries := []string{
+		"SELECT id FROM logs_202601 l  Where l.level = ?",
+		"SELECT id FROM logs_202602 l  Where l.level = ?",
+		"SELECT id FROM logs_202603 l  Where l.level = ?",
+	}
+	if len(queries) != len(exQueries) {
+		t.Fatalf("fan out not expected, queries = %v", queries)
+	}
+	for index, query := range queries {
+		if query != exQueries[index] {
+			t.Errorf("query not expected sql, query = %s", query)
+		}
+	}
+}
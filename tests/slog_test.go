@@ -0,0 +1,90 @@
+//go:build go1.21
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yongpi/putil/plog"
+)
+
+// fixedTimeHandler 把 record 的时间固定成 logTime，slog.Logger 总是用当前时间
+type fixedTimeHandler struct {
+	slog.Handler
+}
+
+func (h fixedTimeHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.Time = logTime
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h fixedTimeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return fixedTimeHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h fixedTimeHandler) WithGroup(name string) slog.Handler {
+	return fixedTimeHandler{h.Handler.WithGroup(name)}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := plog.NewLogger(plog.INFO)
+	logger.Out = &buffer
+	logger.Format = &plog.LogfmtFormatter{TimeFormat: time.RFC3339}
+	logger.AutoCaller = false
+
+	sl := slog.New(fixedTimeHandler{logger.Named("api").Slog().Handler()}).With("svc", "a").WithGroup("req")
+	sl.Info("hi", "id", 1, slog.Group("user", "name", "bob"), slog.Group("", "inline", true))
+	sl.Debug("hidden")
+	exLine := "level=info ts=2020-01-02T03:04:05Z logger=api msg=hi svc=a req.id=1 req.user.name=bob req.inline=true\n"
+	if buffer.String() != exLine {
+		t.Errorf("slog handler not expected, line = %s", buffer.String())
+	}
+
+	buffer.Reset()
+	logger.IsCaller = true
+	logger.Slog().Warn("caller")
+	if !strings.Contains(buffer.String(), "caller=") || !strings.Contains(buffer.String(), "slog_test.go:") {
+		t.Errorf("source should map to caller, line = %s", buffer.String())
+	}
+
+	// 按调用方的包名覆盖级别
+	buffer.Reset()
+	logger.IsCaller = false
+	logger.SetLevels(map[string]plog.LevelType{"putil/tests": plog.DEBUG})
+	slog.New(fixedTimeHandler{logger.Slog().Handler()}).Debug("shown")
+	if buffer.String() != "level=debug ts=2020-01-02T03:04:05Z msg=shown\n" {
+		t.Errorf("package level should apply to slog, line = %s", buffer.String())
+	}
+}
+
+func TestSlogWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewTextHandler(&buffer, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	logger := plog.NewSlogLogger(handler)
+	logger.AutoCaller = false
+
+	logger.Named("db").WithError(errors.New("boom")).WithField("k", "v").Error("failed")
+	logger.Debug("hidden")
+	ctx := plog.ContextWithRequestID(context.Background(), "req-1")
+	logger.WithContext(ctx).Warn("ctx")
+
+	exOut := "level=ERROR msg=failed logger=db error=boom k=v\nlevel=WARN msg=ctx request_id=req-1\n"
+	if buffer.String() != exOut {
+		t.Errorf("slog writer not expected, output = %s", buffer.String())
+	}
+}
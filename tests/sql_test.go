@@ -1,7 +1,11 @@
 package tests
 
 import (
+	"context"
+	"errors"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/yongpi/putil/psql"
 )
@@ -130,3 +134,533 @@ func TestDelete(t *testing.T) {
 	}
 
 }
+
+func TestCase(t *testing.T) {
+	query, args, err := psql.Select("id").
+		ColumnExpr(psql.Case().When(psql.Eq{"status": 1}, "on").Else("off")).
+		From("test").
+		Where(psql.Eq{"name": "sss"}).
+		OrderByExpr(psql.Case("type").When(2, 0).Else(1)).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+
+	exQuery := "SELECT id,CASE WHEN status = ? THEN ? ELSE ? END FROM test " +
+		" Where name = ? " +
+		"ORDER BY CASE type WHEN ? THEN ? ELSE ? END"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue := []interface{}{1, "on", "off", "sss", 2, 0, 1}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+
+	query, args, err = psql.Update("test").
+		Set("price", psql.Case("id").When(1, 10).When(2, 20).Else(psql.Case().When("price > ?", 30, 5).Else(40))).
+		Where(psql.Eq{"id": []int{1, 2}}).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+
+	exQuery = "UPDATE test " +
+		"SET price=CASE id WHEN ? THEN ? WHEN ? THEN ? ELSE CASE WHEN price > ? THEN ? ELSE ? END END " +
+		"WHERE id IN (?,?)"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue = []interface{}{1, 10, 2, 20, 5, 30, 40, 1, 2}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+
+	_, _, err = psql.Select("id").From("test").ColumnExpr(psql.Case()).ToSql()
+	if err == nil {
+		t.Error("case without when should return error")
+	}
+
+	_, _, err = psql.Select("id").From("test").ColumnExpr(psql.Case("type").When(1, "a", 2)).ToSql()
+	if err == nil {
+		t.Error("case value form with when args should return error")
+	}
+
+	// 普通列和表达式列按添加的顺序输出，Scan 的顺序才能对上
+	query, args, err = psql.Select("id").
+		ColumnExpr(psql.Count("*").As("n")).
+		Column("name").
+		ColumnExpr(psql.Case().When("status = ?", "on", 1).Else("off").As("s")).
+		Column("type").
+		From("test").
+		GroupBy("id", "name", "type").
+		ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exQuery = "SELECT id,COUNT(*) AS n,name,CASE WHEN status = ? THEN ? ELSE ? END AS s,type FROM test  GROUP BY id, name, type"
+	if query != exQuery {
+		t.Errorf("column order not expected, query = %s", query)
+	}
+	exValue = []interface{}{1, "on", "off"}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+}
+
+func TestSelectColumnExpr(t *testing.T) {
+	query, args, err := psql.Select("id").
+		Distinct().
+		ColumnExpr(
+			psql.Expr("COALESCE(name, ?)", "none").As("n"),
+			psql.Count("*").As("total"),
+			psql.CountDistinct("type"),
+			psql.Sum("price"), psql.Max("price"), psql.Min("price"), psql.Avg("price"),
+		).
+		From("test").
+		Join("sku on sku.id=test.id AND sku.type = ?", 3).
+		Where("test.status = ?", 1).
+		GroupBy("id").
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+
+	exQuery := "SELECT DISTINCT id,COALESCE(name, ?) AS n,COUNT(*) AS total,COUNT(DISTINCT type)," +
+		"SUM(price),MAX(price),MIN(price),AVG(price) FROM test " +
+		"JOIN sku on sku.id=test.id AND sku.type = ? " +
+		"Where test.status = ? " +
+		"GROUP BY id"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue := []interface{}{"none", 3, 1}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+}
+
+func TestSelectWindow(t *testing.T) {
+	query, args, err := psql.Select("id").
+		ColumnExpr(
+			psql.RowNumber().Over(psql.Window().PartitionBy("user_id").OrderBy("created_at DESC")).As("rn"),
+			psql.Sum("price").Over(psql.Window().OrderBy("id").RowsBetween(psql.UnboundedPreceding, psql.CurrentRow)).As("total"),
+			psql.Lag("price", 1, 0).OverWindow("w"),
+			psql.Lead("price", 2).OverWindow("w"),
+		).
+		From("orders").
+		Where(psql.Eq{"status": 1}).
+		Window("w", psql.Window().PartitionBy("user_id").OrderBy("id").RowsBetween(psql.Preceding(1), psql.Following(1))).
+		OrderByExpr(psql.Rank().Over(psql.Window().OrderBy("price"))).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+
+	exQuery := "SELECT id,ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) AS rn," +
+		"SUM(price) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS total," +
+		"LAG(price, 1, ?) OVER w,LEAD(price, 2) OVER w FROM orders " +
+		" Where status = ? " +
+		"WINDOW w AS (PARTITION BY user_id ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) " +
+		"ORDER BY RANK() OVER (ORDER BY price)"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue := []interface{}{0, 1}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+
+	_, _, err = psql.Select("id").From("orders").Window("w", nil).ToSql()
+	if err == nil {
+		t.Error("window without spec should return error")
+	}
+}
+
+func TestSelectOrderBy(t *testing.T) {
+	query, _, err := psql.Select("id").
+		From("test").
+		OrderByTerms(psql.Asc("created_at"), psql.Desc("id").NullsLast(), psql.Asc("score").NullsFirst()).
+		OrderBy([]string{"name"}...).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery := "SELECT id FROM test  ORDER BY created_at ASC, id IS NULL ASC, id DESC, score IS NULL DESC, score ASC, name"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	whitelist := psql.SortWhitelist{"created": "created_at", "price": "sku.price"}
+	orders, err := whitelist.Parse("-created, +price")
+	if err != nil {
+		t.Error(err)
+	}
+	query, _, err = psql.Select("id").From("test").OrderByTerms(orders...).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	exQuery = "SELECT id FROM test  ORDER BY created_at DESC, sku.price ASC"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	_, err = whitelist.Parse("created,id;drop table test")
+	if err == nil {
+		t.Error("unknown sort key should return error")
+	}
+}
+
+func TestFilterSchema(t *testing.T) {
+	schema := psql.FilterSchema{
+		"status": {Column: "status", Type: psql.StringField},
+		"age":    {Column: "user.age", Type: psql.IntField, Ops: []psql.FilterOp{psql.OpGte, psql.OpLt}},
+		"name":   {Column: "name", Type: psql.StringField, Ops: []psql.FilterOp{psql.OpEq, psql.OpLike}},
+	}
+
+	values, _ := url.ParseQuery("status=a,b&age_gte=18&name_like=foo_&name_like=bar&page=2")
+	cond, err := schema.Parse(values)
+	if err != nil {
+		t.Error(err)
+	}
+	query, args, err := psql.Select("id").From("test").Where(cond).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+
+	exQuery := "SELECT id FROM test  Where (user.age >= ? AND (name LIKE ? ESCAPE '!' OR name LIKE ? ESCAPE '!') AND status IN (?,?))"
+	if query != exQuery {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue := []interface{}{int64(18), "%foo!_%", "%bar%", "a", "b"}
+	if len(args) != len(exValue) {
+		t.Errorf("args not expected len, args = %#v", args)
+	}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+
+	// 没有任何条件时不输出 Where
+	values, _ = url.ParseQuery("page=2&name_like=")
+	cond, err = schema.Parse(values)
+	if err != nil || cond != nil {
+		t.Errorf("empty filter should return nil, cond = %#v, err = %v", cond, err)
+	}
+	query, _, err = psql.Select("id").From("test").Where(cond).ToSql()
+	if err != nil || query != "SELECT id FROM test " {
+		t.Errorf("empty filter should be skipped, query = %s, err = %v", query, err)
+	}
+
+	// 写语句只有空的条件时报错，不会变成全表更新或者删除
+	_, _, err = psql.Update("test").Set("status", "c").Where(cond).ToSql()
+	if err == nil {
+		t.Error("update with only empty filter should return error")
+	}
+	_, _, err = psql.Delete("test").Where(cond).ToSql()
+	if err == nil {
+		t.Error("delete with only empty filter should return error")
+	}
+	_, _, err = psql.NewSqlBuilder(psql.Question).WithSoftDelete("deleted_at").Delete("test").Where(cond).ToSql()
+	if err == nil {
+		t.Error("soft delete with only empty filter should return error")
+	}
+	query, _, err = psql.Delete("test").Where(cond).Where(psql.Eq{"id": 1}).ToSql()
+	if err != nil || query != "DELETE FROM test WHERE id = ?" {
+		t.Errorf("empty filter should be skipped, query = %s, err = %v", query, err)
+	}
+
+	_, err = schema.ParseMap(map[string]string{"age": "18"})
+	if !errors.Is(err, psql.ErrFilterOpNotAllowed) {
+		t.Errorf("op not allowed error expected, err = %v", err)
+	}
+
+	_, err = schema.ParseMap(map[string]string{"age_lt": "abc"})
+	var fe *psql.FilterError
+	if !errors.As(err, &fe) || !errors.Is(err, psql.ErrFilterInvalidValue) || fe.Key != "age_lt" {
+		t.Errorf("invalid value error expected, err = %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	base := psql.Select("id", "name").From("test").Where(psql.Eq{"status": 1}).Limit(10)
+	// 让底层数组有空余容量，派生语句 append 时才会暴露共享问题
+	base.Wheres = append(make([]psql.SqlCond, 0, 8), base.Wheres...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = base.Clone().Where(psql.Eq{"type": 2}).ToSql()
+	}()
+	first := base.Clone().Where(psql.Eq{"type": 1}).Limit(20)
+	second := base.Clone().Where(psql.Gt{"price": 100})
+	<-done
+
+	for _, item := range []struct {
+		st      *psql.SelectStatement
+		exQuery string
+	}{
+		{base, "SELECT id,name FROM test  Where status = ? LIMIT 10"},
+		{first, "SELECT id,name FROM test  Where status = ? AND type = ? LIMIT 20"},
+		{second, "SELECT id,name FROM test  Where status = ? AND price > ? LIMIT 10"},
+	} {
+		query, _, err := item.st.ToSql()
+		if err != nil {
+			t.Error(err)
+		}
+		if query != item.exQuery {
+			t.Errorf("query not expected sql, query = %s", query)
+		}
+	}
+
+	insert := psql.Insert("test").Column("id").Value(1)
+	clone := insert.Clone().Value(2)
+	clone.Values[0][0] = 3
+	query, args, err := insert.ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "INSERT INTO test (id) VALUES (?)" || len(args) != 1 || args[0] != 1 {
+		t.Errorf("insert changed by clone, query = %s, args = %#v", query, args)
+	}
+
+	// 表达式列是指针，clone 之后修改原来的表达式不影响 clone
+	ce := psql.Case().When("score > ?", "high", 60)
+	spec := psql.Window().PartitionBy("class")
+	expr := psql.Select("id").From("test").ColumnExpr(ce.As("level"), psql.RowNumber().Over(spec).As("rn"))
+	exprClone := expr.Clone()
+	ce.Else("low")
+	spec.OrderBy("score DESC")
+	query, args, err = exprClone.ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "SELECT id,CASE WHEN score > ? THEN ? END AS level,ROW_NUMBER() OVER (PARTITION BY class) AS rn FROM test " || len(args) != 2 {
+		t.Errorf("select changed by clone, query = %s, args = %#v", query, args)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	builder := psql.NewSqlBuilder(psql.Question).WithSoftDelete("deleted_at")
+
+	query, _, err := builder.Select("id").From("test").Where(psql.Eq{"name": "sss"}).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "SELECT id FROM test  Where name = ? AND test.deleted_at IS NULL" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = builder.Select("u.id").From("users u").LeftJoin("orders o ON o.user_id = u.id").Where(psql.Eq{"u.id": 1}).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "SELECT u.id FROM users u LEFT JOIN orders o ON o.user_id = u.id Where u.id = ? AND u.deleted_at IS NULL" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, _, err = builder.Select("id").From("test").WithDeleted().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "SELECT id FROM test " {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+
+	query, args, err := builder.Delete("test").Where(psql.Eq{"id": 1}).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "UPDATE test SET deleted_at=? WHERE id = ? AND deleted_at IS NULL" || len(args) != 2 {
+		t.Errorf("query not expected sql, query = %s, args = %#v", query, args)
+	}
+	if _, ok := args[0].(time.Time); !ok || args[1] != 1 {
+		t.Errorf("args not expected value, args = %#v", args)
+	}
+
+	query, _, err = builder.Delete("test").Where(psql.Eq{"id": 1}).Hard().ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "DELETE FROM test WHERE id = ?" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	builder := psql.NewSqlBuilder(psql.Question)
+	st := builder.UpdateVersioned("test", 3).Set("name", "sss").Where(psql.Eq{"id": 1})
+
+	query, args, err := st.ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "UPDATE test SET name=?,version=version + 1 WHERE id = ? AND version = ?" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	exValue := []interface{}{"sss", 1, 3}
+	for index, value := range args {
+		if exValue[index] != value {
+			t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+		}
+	}
+
+	var affected int64
+	db, _ := newFakeDB(func(query string, args []interface{}) (*fakeResult, error) {
+		return &fakeResult{Affected: affected}, nil
+	})
+	defer db.Close()
+
+	_, err = psql.Exec(context.Background(), db, st)
+	var conflict *psql.ConflictError
+	if !errors.As(err, &conflict) || conflict.Version != 3 {
+		t.Errorf("conflict error expected, err = %v", err)
+	}
+
+	affected = 1
+	if _, err = psql.Exec(context.Background(), db, st); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScoped(t *testing.T) {
+	builder := psql.NewSqlBuilder(psql.Question).Scoped(psql.Eq{"tenant_id": 7})
+
+	items := []struct {
+		st      psql.SqlStatement
+		exQuery string
+		exValue []interface{}
+	}{
+		{
+			builder.Select("id").From("test").Where(psql.Eq{"name": "sss"}),
+			"SELECT id FROM test  Where name = ? AND test.tenant_id = ?",
+			[]interface{}{"sss", 7},
+		},
+		{
+			builder.Update("test").Set("name", "sss").Where(psql.Eq{"id": 1}),
+			"UPDATE test SET name=? WHERE id = ? AND tenant_id = ?",
+			[]interface{}{"sss", 1, 7},
+		},
+		{
+			builder.Delete("test"),
+			"DELETE FROM test WHERE tenant_id = ?",
+			[]interface{}{7},
+		},
+		{
+			builder.Insert("test").Column("id", "name").Value(1, "a").Value(2, "b"),
+			"INSERT INTO test (id,name,tenant_id) VALUES (?,?,?),(?,?,?)",
+			[]interface{}{1, "a", 7, 2, "b", 7},
+		},
+		{
+			builder.Insert("test").Column("tenant_id", "id").Value(7, 1),
+			"INSERT INTO test (tenant_id,id) VALUES (?,?)",
+			[]interface{}{7, 1},
+		},
+		{
+			builder.Select("id").From("test").Where("a = ? OR b = ?", 1, 2),
+			"SELECT id FROM test  Where (a = ? OR b = ?) AND test.tenant_id = ?",
+			[]interface{}{1, 2, 7},
+		},
+		{
+			builder.Select("u.id", "o.id").From("users u").Join("orders o ON o.user_id = u.id").Where(psql.Eq{"o.status": 1}),
+			"SELECT u.id,o.id FROM users u JOIN orders o ON o.user_id = u.id Where o.status = ? AND u.tenant_id = ?",
+			[]interface{}{1, 7},
+		},
+		{
+			builder.Delete("test").Where(psql.Or{psql.Expr("a = ? OR b = ?", 1, 2)}),
+			"DELETE FROM test WHERE (a = ? OR b = ?) AND tenant_id = ?",
+			[]interface{}{1, 2, 7},
+		},
+		{
+			builder.Update("test").Set("tenant_id", int64(7)).Where(psql.Eq{"id": 1}),
+			"UPDATE test SET tenant_id=? WHERE id = ? AND tenant_id = ?",
+			[]interface{}{int64(7), 1, 7},
+		},
+		{
+			builder.Select("id").From("test").Unscoped(),
+			"SELECT id FROM test ",
+			nil,
+		},
+	}
+	for _, item := range items {
+		query, args, err := item.st.ToSql()
+		if err != nil {
+			t.Error(err)
+		}
+		if query != item.exQuery {
+			t.Errorf("query not expected sql, query = %s", query)
+		}
+		if len(args) != len(item.exValue) {
+			t.Errorf("args not expected len, args = %#v", args)
+		}
+		for index, value := range args {
+			if item.exValue[index] != value {
+				t.Errorf("args not expected value, args = %#v, value = %v", args, value)
+			}
+		}
+	}
+
+	bypass := []psql.SqlStatement{
+		builder.Insert("test").Column("tenant_id", "id").Value(8, 1),
+		builder.Update("test").Set("tenant_id", 8),
+		builder.Insert("test").Value(1),
+		psql.NewSqlBuilder(psql.Question).Scoped(psql.Eq{"tenant_id": nil}).Select("id").From("test"),
+	}
+	for _, st := range bypass {
+		if _, _, err := st.ToSql(); err == nil {
+			t.Errorf("bypass scope should return error, statement = %#v", st)
+		}
+	}
+}
+
+func TestDollarPlaceHolder(t *testing.T) {
+	builder := psql.NewSqlBuilder(psql.Postgres.PlaceHolder())
+
+	query, args, err := builder.Select("id").From("test").
+		Where(psql.Eq{"name": "a'?'"}).
+		Where("status IN (?, ?) AND tags ?? 'x'", 1, 2).
+		ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "SELECT id FROM test  Where name = $1 AND status IN ($2, $3) AND tags ? 'x'" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+	if len(args) != 3 {
+		t.Errorf("args not expected value, args = %#v", args)
+	}
+
+	query, _, err = builder.Update("test").Set("name", "a").Where(psql.Eq{"id": 1}).ToSql()
+	if err != nil {
+		t.Error(err)
+	}
+	if query != "UPDATE test SET name=$1 WHERE id = $2" {
+		t.Errorf("query not expected sql, query = %s", query)
+	}
+}
@@ -9,8 +9,20 @@ import (
 	"github.com/yongpi/putil/plog"
 )
 
+// defaultLogger WaitGroupWrapper 和 TimingWheel 没有注入 logger 时使用
+var defaultLogger = plog.Named("timing_wheel")
+
 type WaitGroupWrapper struct {
 	sync.WaitGroup
+	// 为空时使用 plog.Named("timing_wheel")
+	Logger plog.FieldLogger
+}
+
+func (s *WaitGroupWrapper) logger() plog.FieldLogger {
+	if s.Logger == nil {
+		return defaultLogger
+	}
+	return s.Logger
 }
 
 func (s *WaitGroupWrapper) SafeRun(fun func()) {
@@ -20,7 +32,7 @@ func (s *WaitGroupWrapper) SafeRun(fun func()) {
 		defer func() {
 			// 如果发生 panic 则执行不到 Done 方法，需要 recover
 			if err := recover(); err != nil {
-				plog.Errorf("[WaitGroupWrapper] fun panic!, err = %#v", err)
+				s.logger().Errorf("[WaitGroupWrapper] fun panic!, err = %#v", err)
 				s.Done()
 			}
 		}()
@@ -47,11 +59,28 @@ type TimingWheel struct {
 	close       chan any
 }
 
-func NewTimingWheel(tick time.Duration, wheelSize int64) *TimingWheel {
+type WheelConfig struct {
+	logger plog.FieldLogger
+}
+
+type WheelOption func(cfg *WheelConfig)
+
+// Logger 注入 logger，默认使用 plog.Named("timing_wheel")
+func Logger(logger plog.FieldLogger) WheelOption {
+	return func(cfg *WheelConfig) {
+		cfg.logger = logger
+	}
+}
+
+func NewTimingWheel(tick time.Duration, wheelSize int64, options ...WheelOption) *TimingWheel {
 	if tick < time.Millisecond {
 		panic(fmt.Sprintf("timing wheel tick too small, must >= 1ms"))
 	}
 
+	cfg := &WheelConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
 	tickMs := int64(tick / time.Millisecond)
 
 	firstWheel := &Wheel{
@@ -65,9 +94,10 @@ func NewTimingWheel(tick time.Duration, wheelSize int64) *TimingWheel {
 	cc := make(chan any, 1)
 
 	timeWheel := &TimingWheel{
-		wheel: firstWheel,
-		queue: queue,
-		close: cc,
+		WaitGroupWrapper: WaitGroupWrapper{Logger: cfg.logger},
+		wheel:            firstWheel,
+		queue:            queue,
+		close:            cc,
 	}
 
 	timeWheel.currentTime.Store(TruncateTime(time.Now().UnixMilli(), tickMs))